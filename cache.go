@@ -0,0 +1,188 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheGroup responses invalidated together when the matching realtime checksum changes
+type cacheGroup int
+
+const (
+	// cacheDevices invalidated by device_data_checksum
+	cacheDevices cacheGroup = iota
+	// cacheMonitor invalidated by monitor_overview_checksum
+	cacheMonitor
+)
+
+// CacheStats response cache statistics
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// cacheScope checksums are tracked per monitor
+type cacheScope struct {
+	group   cacheGroup
+	monitor string
+}
+
+type cacheEntry struct {
+	scope   cacheScope
+	body    []byte
+	expires time.Time
+}
+
+type responseCache struct {
+	mutex     sync.Mutex
+	ttl       time.Duration
+	entries   map[string]cacheEntry
+	checksums map[cacheScope]string
+	hits      uint64
+	misses    uint64
+}
+
+// WithResponseCache serve DevicesOverview, RateZone and other slow changing responses from memory
+// until the realtime feed reports a checksum change or ttl elapses. a ttl of 0 means entries only
+// expire on checksum changes, which requires a realtime reader to be running
+func WithResponseCache(ttl time.Duration) Option {
+	return func(s *SenseApi) error {
+		if ttl < 0 {
+			return errors.New("cache ttl must not be negative")
+		}
+		s.cache = &responseCache{
+			ttl:       ttl,
+			entries:   map[string]cacheEntry{},
+			checksums: map[cacheScope]string{},
+		}
+		return nil
+	}
+}
+
+// CacheStats hit and miss counts of the response cache, zero if caching is disabled
+func (s *SenseApi) CacheStats() CacheStats {
+	return s.cache.stats()
+}
+
+// cachedGet GET u of monitor into parseType, using the response cache if enabled
+func (s *SenseApi) cachedGet(ctx context.Context, group cacheGroup, monitor, u string, parseType interface{}) (err error) {
+	if b, ok := s.cache.get(u); ok {
+		return json.Unmarshal(b, parseType)
+	}
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return err
+	}
+	b, err := readRes(res)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, parseType)
+	if err != nil {
+		return err
+	}
+	s.cache.set(cacheScope{group, monitor}, u, b)
+	return err
+}
+
+func (c *responseCache) get(key string) (b []byte, ok bool) {
+	if c == nil {
+		return b, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[key]
+	if ok && c.ttl > 0 && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	if ok {
+		c.hits++
+		return e.body, ok
+	}
+	c.misses++
+	return b, ok
+}
+
+func (c *responseCache) set(scope cacheScope, key string, b []byte) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[key] = cacheEntry{scope: scope, body: b, expires: time.Now().Add(c.ttl)}
+}
+
+// setChecksum record the checksum the cached scope data corresponds to
+func (c *responseCache) setChecksum(scope cacheScope, checksum string) {
+	if c == nil || checksum == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.checksums[scope] = checksum
+}
+
+// invalidate drop all entries of scope
+func (c *responseCache) invalidate(scope cacheScope) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidateLocked(scope)
+}
+
+func (c *responseCache) invalidateLocked(scope cacheScope) {
+	for k, e := range c.entries {
+		if e.scope == scope {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// observe invalidate groups of the frame's monitor whose checksum changed in a realtime frame
+func (c *responseCache) observe(msg *RealTime) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	monitor := strconv.Itoa(msg.MonitorId)
+	for group, checksum := range map[cacheGroup]string{
+		cacheDevices: msg.Payload.DeviceDataChecksum,
+		cacheMonitor: msg.Payload.MonitorOverviewChecksum,
+	} {
+		scope := cacheScope{group, monitor}
+		if checksum == "" || c.checksums[scope] == checksum {
+			continue
+		}
+		c.checksums[scope] = checksum
+		c.invalidateLocked(scope)
+	}
+}
+
+// clear drop all entries and checksums
+func (c *responseCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[string]cacheEntry{}
+	c.checksums = map[cacheScope]string{}
+}
+
+func (c *responseCache) stats() (st CacheStats) {
+	if c == nil {
+		return st
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
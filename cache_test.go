@@ -0,0 +1,72 @@
+package sense
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	f := newFakeSense(t)
+	var calls int32
+	f.mux.HandleFunc("/app/monitors/1/devices/overview", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte(`{"devices":[{"id":"d1","name":"Fridge"}],"device_data_checksum":"c1"}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithResponseCache(0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 0; i < 3; i++ {
+		do, err := s.DevicesOverview(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(do.Devices) != 1 || do.Devices[0].Name != "Fridge" {
+			t.Fatalf("unexpected overview %+v", do)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+	if st := s.CacheStats(); st.Hits != 2 || st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("stats = %+v, want 2 hits 1 miss", st)
+	}
+
+	msg := &RealTime{MonitorId: 1}
+	msg.Payload.DeviceDataChecksum = "c1"
+	s.cache.observe(msg)
+	_, _ = s.DevicesOverview(true)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("unchanged checksum: calls = %d, want 1", n)
+	}
+	msg.Payload.DeviceDataChecksum = "c2"
+	s.cache.observe(msg)
+	_, _ = s.DevicesOverview(true)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("changed checksum: calls = %d, want 2", n)
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	f := newFakeSense(t)
+	var calls int32
+	f.mux.HandleFunc("/app/monitors/1/devices/overview", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithResponseCache(20*time.Millisecond))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	_, _ = s.DevicesOverview(false)
+	_, _ = s.DevicesOverview(false)
+	time.Sleep(30 * time.Millisecond)
+	_, _ = s.DevicesOverview(false)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
@@ -0,0 +1,262 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadProfile, they override values from the config file
+const (
+	EnvConfig      = "SENSE_CONFIG"
+	EnvProfile     = "SENSE_PROFILE"
+	EnvEmail       = "SENSE_EMAIL"
+	EnvPassword    = "SENSE_PASSWORD"
+	EnvTOTPSecret  = "SENSE_TOTP_SECRET"
+	EnvMonitorId   = "SENSE_MONITOR_ID"
+	EnvBaseURL     = "SENSE_BASE_URL"
+	EnvRealtimeURL = "SENSE_REALTIME_URL"
+)
+
+const defaultProfile = "default"
+
+// Profile credentials and client settings of one named profile
+// String and GoString redact the password and totp secret so profiles can be logged safely
+type Profile struct {
+	Name        string
+	Email       string
+	Password    string `json:"-"`
+	TOTPSecret  string `json:"-"`
+	MonitorId   int
+	BaseURL     string
+	RealtimeURL string
+	UserAgent   string
+}
+
+// LoadProfile read profile name from the config file at path and apply environment overrides
+//
+// an empty name uses $SENSE_PROFILE or "default", an empty path uses $SENSE_CONFIG and if that is
+// unset too only the environment is read. the file format is picked by extension: .json, .yaml/.yml
+// or .toml, each holding profiles by name with the keys email, password, totp_secret, monitor_id,
+// base_url, realtime_url and user_agent, e.g. in yaml
+//
+//	default:
+//	  email: me@example.com
+//	  password: secret
+//	  monitor_id: 12345
+//
+// values must be strings or numbers
+func LoadProfile(path, name string) (p *Profile, err error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	p = &Profile{Name: name}
+	if path != "" {
+		profiles, err := readConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		values, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		for k, v := range values {
+			err = p.set(k, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: profile %q: %w", path, name, err)
+			}
+		}
+	}
+	for env, key := range map[string]string{
+		EnvEmail:       "email",
+		EnvPassword:    "password",
+		EnvTOTPSecret:  "totp_secret",
+		EnvMonitorId:   "monitor_id",
+		EnvBaseURL:     "base_url",
+		EnvRealtimeURL: "realtime_url",
+	} {
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			err = p.set(key, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+	return p, err
+}
+
+// Options client options for the profile settings, pass them to NewSenseApi with Email and Password
+// a totp secret configures a TOTPProvider and email and password a CredentialProvider for re-login
+func (p *Profile) Options() (opts []Option, err error) {
+	if p.Email != "" && p.Password != "" {
+		opts = append(opts, WithCredentialProvider(p))
+	}
+	if p.BaseURL != "" {
+		opts = append(opts, WithBaseURL(p.BaseURL))
+	}
+	if p.RealtimeURL != "" {
+		opts = append(opts, WithRealtimeURL(p.RealtimeURL))
+	}
+	if p.UserAgent != "" {
+		opts = append(opts, WithUserAgent(p.UserAgent))
+	}
+	if p.MonitorId != 0 {
+		opts = append(opts, WithDefaultMonitor(p.MonitorId))
+	}
+	if p.TOTPSecret != "" {
+		totp, err := NewTOTPProvider(p.TOTPSecret)
+		if err != nil {
+			return opts, errors.New("invalid totp secret")
+		}
+		opts = append(opts, WithMFAProvider(totp))
+	}
+	return opts, err
+}
+
+// Credentials CredentialProvider returning the profile email and password
+func (p *Profile) Credentials(ctx context.Context) (username, password string, err error) {
+	if p.Email == "" || p.Password == "" {
+		return "", "", fmt.Errorf("profile %q has no email and password", p.Name)
+	}
+	return p.Email, p.Password, nil
+}
+
+func (p Profile) String() string {
+	return fmt.Sprintf("Profile{Name: %q, Email: %q, Password: %s, TOTPSecret: %s, MonitorId: %d, BaseURL: %q, RealtimeURL: %q, UserAgent: %q}",
+		p.Name, p.Email, redact(p.Password), redact(p.TOTPSecret), p.MonitorId, p.BaseURL, p.RealtimeURL, p.UserAgent)
+}
+
+func (p Profile) GoString() string {
+	return "sense." + p.String()
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return `""`
+	}
+	return "<redacted>"
+}
+
+// set assign a config key, errors never include the value as it may be a secret
+func (p *Profile) set(key, value string) (err error) {
+	switch key {
+	case "email":
+		p.Email = value
+	case "password":
+		p.Password = value
+	case "totp_secret":
+		p.TOTPSecret = value
+	case "monitor_id":
+		p.MonitorId, err = strconv.Atoi(value)
+		if err != nil {
+			return errors.New("monitor_id must be an integer")
+		}
+	case "base_url":
+		p.BaseURL = value
+	case "realtime_url":
+		p.RealtimeURL = value
+	case "user_agent":
+		p.UserAgent = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return err
+}
+
+// readConfig parse the profiles in path into key value pairs by profile name
+func readConfig(path string) (profiles map[string]map[string]string, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		profiles, err = parseJSONConfig(b)
+	case ".yaml", ".yml":
+		profiles, err = parseYAMLConfig(b)
+	case ".toml":
+		profiles, err = parseTOMLConfig(b)
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q, use .json, .yaml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return profiles, err
+}
+
+func parseJSONConfig(b []byte) (profiles map[string]map[string]string, err error) {
+	raw := map[string]interface{}{}
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		// json syntax errors only carry an offset, type errors name the go type, neither echoes values
+		return nil, err
+	}
+	return configProfiles(raw)
+}
+
+func parseYAMLConfig(b []byte) (profiles map[string]map[string]string, err error) {
+	raw := map[string]interface{}{}
+	// decoding into interface{} leaves only syntax errors, yaml type errors would quote the value
+	err = yaml.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, err
+	}
+	return configProfiles(raw)
+}
+
+func parseTOMLConfig(b []byte) (profiles map[string]map[string]string, err error) {
+	raw := map[string]interface{}{}
+	_, err = toml.Decode(string(b), &raw)
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		// the parse message may quote the offending value
+		return nil, fmt.Errorf("toml: syntax error on line %d", perr.Position.Line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configProfiles(raw)
+}
+
+// configProfiles convert decoded profiles to key value pairs, values must be strings or numbers
+func configProfiles(raw map[string]interface{}) (profiles map[string]map[string]string, err error) {
+	profiles = map[string]map[string]string{}
+	for name, values := range raw {
+		m, ok := values.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profile %q must be a table of keys", name)
+		}
+		profiles[name] = map[string]string{}
+		for k, v := range m {
+			switch v := v.(type) {
+			case string:
+				profiles[name][k] = v
+			case int:
+				profiles[name][k] = strconv.Itoa(v)
+			case int64:
+				profiles[name][k] = strconv.FormatInt(v, 10)
+			case float64:
+				profiles[name][k] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				return nil, fmt.Errorf("profile %q: %s must be a string or number", name, k)
+			}
+		}
+	}
+	return profiles, err
+}
@@ -0,0 +1,128 @@
+package sense
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{
+			"default": {"email": "other@example.com"},
+			"home": {"email": "me@example.com", "password": "p#ss \"word\"", "monitor_id": 42, "base_url": "http://localhost:1"}
+		}`,
+		"config.yaml": `# sense profiles
+default:
+  email: other@example.com
+home:
+  email: me@example.com # login
+  password: "p#ss \"word\""
+  monitor_id: 42
+  base_url: 'http://localhost:1'
+`,
+		"config.toml": `[default]
+email = "other@example.com"
+
+[home]
+email = "me@example.com"
+password = "p#ss \"word\"" # quoted
+monitor_id = 42
+base_url = 'http://localhost:1'
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		p, err := LoadProfile(path, "home")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := Profile{Name: "home", Email: "me@example.com", Password: `p#ss "word"`, MonitorId: 42, BaseURL: "http://localhost:1"}
+		if *p != want {
+			t.Errorf("%s: profile = %#v, want %#v", name, p, want)
+		}
+		if _, err = LoadProfile(path, "missing"); err == nil {
+			t.Errorf("%s: expected error for missing profile", name)
+		}
+	}
+
+	// flow mappings, '' escapes and dotted keys are valid too
+	other := map[string]string{
+		"flow.yaml":   "home: {email: me@example.com, password: 'it''s', monitor_id: 42}\n",
+		"dotted.toml": "home.email = \"me@example.com\"\nhome.password = \"it's\"\nhome.monitor_id = 42\n",
+	}
+	for name, content := range other {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		p, err := LoadProfile(path, "home")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Email != "me@example.com" || p.Password != "it's" || p.MonitorId != 42 {
+			t.Errorf("%s: profile = %#v", name, p)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.yaml")
+	_ = ioutil.WriteFile(bad, []byte("default:\n  pasword: hunter2\n"), 0600)
+	if _, err := LoadProfile(bad, ""); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("unknown key err = %v, want error without the value", err)
+	}
+	for name, content := range map[string]string{
+		"scalar.yaml": "default: hunter2\n",
+		"syntax.toml": "[default]\npassword = hunter2\n",
+	} {
+		path := filepath.Join(dir, name)
+		_ = ioutil.WriteFile(path, []byte(content), 0600)
+		if _, err := LoadProfile(path, ""); err == nil || strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("%s: err = %v, want error without the value", name, err)
+		}
+	}
+}
+
+func TestLoadProfileEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	_ = ioutil.WriteFile(path, []byte("work:\n  email: file@example.com\n  password: file\n"), 0600)
+	t.Setenv(EnvConfig, path)
+	t.Setenv(EnvProfile, "work")
+	t.Setenv(EnvPassword, "hunter2")
+	t.Setenv(EnvTOTPSecret, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	t.Setenv(EnvMonitorId, "7")
+	p, err := LoadProfile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "work" || p.Email != "file@example.com" || p.Password != "hunter2" || p.MonitorId != 7 {
+		t.Errorf("profile = %#v", p)
+	}
+	for _, s := range []string{p.String(), fmt.Sprintf("%v %+v %#v", p, *p, p)} {
+		if strings.Contains(s, "hunter2") || strings.Contains(s, "GEZDGNBV") {
+			t.Errorf("secret leaked in %s", s)
+		}
+	}
+
+	opts, err := p.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.mfaProvider == nil || s.defaultMonitorId != 7 {
+		t.Error("profile options not applied")
+	}
+
+	t.Setenv(EnvMonitorId, "seven")
+	if _, err = LoadProfile("", ""); err == nil {
+		t.Error("expected error for invalid monitor id")
+	}
+}
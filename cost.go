@@ -0,0 +1,60 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ElectricityCost price sense uses to compute the cost of usage and production
+type ElectricityCost struct {
+	// Cost flat price per kWh
+	Cost float64 `json:"cost"`
+	// SellBackRate price per kWh paid for energy sent to the grid
+	SellBackRate float64 `json:"sell_back_rate"`
+	// UserSetCost and UserSetSellBackRate false when sense uses a regional default
+	UserSetCost         bool `json:"user_set_cost"`
+	UserSetSellBackRate bool `json:"user_set_sell_back_rate"`
+	// CycleStart day of the month the billing cycle starts
+	CycleStart int `json:"cycle_start"`
+}
+
+// ElectricityCost price per kWh, sell back rate and billing cycle start of the monitor
+func (s *SenseApi) ElectricityCost(ctx context.Context) (ec *ElectricityCost, err error) {
+	u := fmt.Sprintf("%s/app/monitors/%s/electricity_cost", s.baseUrl, s.getMonitorId(ctx))
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return ec, err
+	}
+	ec = &ElectricityCost{}
+	err = parseRes(res, ec)
+	return ec, err
+}
+
+// SetElectricityCost replace the electricity cost of the monitor, unset the UserSet flags to go back to
+// the regional default. cached responses of the monitor are dropped as their costs change
+func (s *SenseApi) SetElectricityCost(ctx context.Context, cost ElectricityCost) (ec *ElectricityCost, err error) {
+	if cost.Cost < 0 || cost.SellBackRate < 0 {
+		return ec, errors.New("cost and sell back rate must not be negative")
+	}
+	if cost.CycleStart < 1 || cost.CycleStart > 31 {
+		return ec, fmt.Errorf("cycle start %d is not a day of the month", cost.CycleStart)
+	}
+	b, err := json.Marshal(cost)
+	if err != nil {
+		return ec, err
+	}
+	monitorId := s.getMonitorId(ctx)
+	u := fmt.Sprintf("%s/app/monitors/%s/electricity_cost", s.baseUrl, monitorId)
+	res, err := s.apiRequest(ctx, http.MethodPut, u, jsonContentType, string(b))
+	if err != nil {
+		return ec, err
+	}
+	s.cache.invalidate(cacheScope{cacheMonitor, monitorId})
+	s.cache.invalidate(cacheScope{cacheDevices, monitorId})
+	ec = &ElectricityCost{}
+	err = parseRes(res, ec)
+	return ec, err
+}
@@ -0,0 +1,60 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestElectricityCost(t *testing.T) {
+	f := newFakeSense(t)
+	var stored ElectricityCost
+	f.mux.HandleFunc("/app/monitors/1/electricity_cost", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&stored); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		} else if stored.CycleStart == 0 {
+			stored = ElectricityCost{Cost: 12.5, SellBackRate: 3, CycleStart: 1}
+		}
+		_ = json.NewEncoder(w).Encode(stored)
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	ec, err := s.ElectricityCost(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.Cost != 12.5 || ec.UserSetCost {
+		t.Errorf("cost = %+v", ec)
+	}
+
+	ec.Cost, ec.UserSetCost, ec.CycleStart = 21.3, true, 15
+	updated, err := s.SetElectricityCost(ctx, *ec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updated != *ec {
+		t.Errorf("updated = %+v, want %+v", updated, ec)
+	}
+	ec.CycleStart = 0
+	if _, err = s.SetElectricityCost(ctx, *ec); err == nil {
+		t.Error("expected error for invalid cycle start")
+	}
+
+	var m Monitor
+	err = json.Unmarshal([]byte(`{"id":1,"attributes":{"electricity_cost":{"cost":9.5,"cycle_start":3}}}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.Attributes.ElectricityCost; c == nil || c.Cost != 9.5 || c.CycleStart != 3 {
+		t.Errorf("monitor electricity cost = %+v", c)
+	}
+}
@@ -0,0 +1,90 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// reloginInterval minimum time between two full logins triggered by rejected renewals
+const reloginInterval = time.Minute
+
+// CredentialProvider supplies the username and password for a full login when the refresh token
+// is rejected, see WithCredentialProvider
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (username, password string, err error)
+}
+
+// CredentialProviderFunc adapter to use a function as CredentialProvider
+type CredentialProviderFunc func(ctx context.Context) (username, password string, err error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (username, password string, err error) {
+	return f(ctx)
+}
+
+// StaticCredentials CredentialProvider always returning username and password
+func StaticCredentials(username, password string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (string, string, error) {
+		return username, password, nil
+	})
+}
+
+// WithCredentialProvider log in again with the credentials from p when renewing the access token
+// is rejected, e.g. because the refresh token was revoked. accounts with mfa also need an MFAProvider.
+// to avoid login loops a full login is attempted at most once a minute
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(s *SenseApi) error {
+		if p == nil {
+			return errors.New("credential provider must not be nil")
+		}
+		s.credentials = p
+		return nil
+	}
+}
+
+// ReloginError returned when renewing was rejected and the fallback login failed or was skipped
+// errors.Is(err, ErrReloginFailed) matches it, Unwrap returns the login error
+type ReloginError struct {
+	RenewErr error
+	Err      error
+}
+
+func (e *ReloginError) Error() string {
+	return fmt.Sprintf("renew failed: %v, re-login failed: %v", e.RenewErr, e.Err)
+}
+
+func (e *ReloginError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ReloginError) Is(target error) bool {
+	return target == ErrReloginFailed
+}
+
+// renewOrRelogin renew the access token, falling back to a full login if the renewal is rejected
+// and a CredentialProvider is configured. callers must be the single in flight renewal
+func (s *SenseApi) renewOrRelogin(ctx context.Context) (err error) {
+	err = s.renew(ctx)
+	if err == nil || s.credentials == nil || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	renewErr := err
+	s.renewMutex.Lock()
+	last := s.lastRelogin
+	if !last.IsZero() && time.Since(last) < reloginInterval {
+		s.renewMutex.Unlock()
+		return &ReloginError{RenewErr: renewErr, Err: fmt.Errorf("last attempt %s ago", time.Since(last).Round(time.Second))}
+	}
+	s.lastRelogin = time.Now()
+	s.renewMutex.Unlock()
+
+	username, password, err := s.credentials.Credentials(ctx)
+	if err == nil {
+		err = s.authenticate(ctx, username, password, true)
+	}
+	if err != nil {
+		return &ReloginError{RenewErr: renewErr, Err: err}
+	}
+	return err
+}
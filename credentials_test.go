@@ -0,0 +1,62 @@
+package sense
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReloginFallback(t *testing.T) {
+	f := newFakeSense(t)
+	sess := Session{
+		AccessToken:  testToken(time.Now().Add(-time.Hour)),
+		RefreshToken: "revoked",
+		UserId:       2,
+		Monitors:     []Monitor{{Id: 1}},
+	}
+	opts := append(f.options(), WithLazyConnect(), WithRetryPolicy(NoRetry))
+	s, err := NewSenseApiFromSession(sess, append(opts, WithCredentialProvider(StaticCredentials("user", "pass")))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logins []AuthEvent
+	s.OnLogin(func(ev AuthEvent) {
+		logins = append(logins, ev)
+	})
+	if _, err = s.RateZone(); err != nil {
+		t.Fatalf("expected transparent re-login: %v", err)
+	}
+	if len(logins) != 1 || !logins[0].Relogin {
+		t.Errorf("login events = %+v, want one re-login", logins)
+	}
+	if s.Session().RefreshToken != "refresh" {
+		t.Error("session not replaced by the re-login")
+	}
+
+	// a second rejection right after a re-login must not log in again
+	s.authMutex.Lock()
+	s.authRes.RefreshToken = "revoked"
+	s.authMutex.Unlock()
+	err = s.RenewToken()
+	if !errors.Is(err, ErrReloginFailed) {
+		t.Errorf("err = %v, want ErrReloginFailed", err)
+	}
+
+	f.mfaCode = "123456"
+	s, err = NewSenseApiFromSession(sess, append(opts, WithCredentialProvider(StaticCredentials("user", "pass")))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.RateZone()
+	if !errors.Is(err, ErrReloginFailed) || !errors.Is(err, ErrMFARequired) {
+		t.Errorf("err = %v, want ErrReloginFailed wrapping ErrMFARequired", err)
+	}
+
+	s, err = NewSenseApiFromSession(sess, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RateZone(); errors.Is(err, ErrReloginFailed) || !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want plain unauthorized without credential provider", err)
+	}
+}
@@ -0,0 +1,54 @@
+package sense
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+const (
+	deviceIdLength   = 128
+	deviceIdAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// WithDeviceID identify the client to sense as id instead of the generated or saved device id
+func WithDeviceID(id string) Option {
+	return func(s *SenseApi) error {
+		if id == "" {
+			return errors.New("device id must not be empty")
+		}
+		s.deviceId = id
+		s.fixedDeviceId = true
+		return nil
+	}
+}
+
+// DeviceID device identity sent as x-sense-device-id and with the realtime feed,
+// it is saved with the session so resumed sessions keep their identity
+func (s *SenseApi) DeviceID() string {
+	return s.clientDeviceId()
+}
+
+func (s *SenseApi) clientDeviceId() string {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.deviceId
+}
+
+// newDeviceId random id in the format of the sense web app, 128 characters of [0-9a-z]
+func newDeviceId() (string, error) {
+	id := make([]byte, 0, deviceIdLength)
+	buf := make([]byte, deviceIdLength)
+	// 252 is the largest multiple of 36 below 256, rejecting bytes above it keeps the distribution uniform
+	for len(id) < deviceIdLength {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if b < 252 && len(id) < deviceIdLength {
+				id = append(id, deviceIdAlphabet[int(b)%len(deviceIdAlphabet)])
+			}
+		}
+	}
+	return string(id), nil
+}
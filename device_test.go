@@ -0,0 +1,56 @@
+package sense
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestDeviceID(t *testing.T) {
+	f := newFakeSense(t)
+	store := NewMemoryTokenStore()
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	id := s.DeviceID()
+	if !regexp.MustCompile(`^[0-9a-z]{128}$`).MatchString(id) {
+		t.Fatalf("device id %q is not 128 characters of [0-9a-z]", id)
+	}
+	if _, err = s.ReadMessageContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	if len(f.deviceIds) != 1 || !f.deviceIds[id] {
+		t.Errorf("device ids seen = %v, want only %q", f.deviceIds, id)
+	}
+	f.mu.Unlock()
+
+	other, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.DeviceID() == id {
+		t.Error("two clients generated the same device id")
+	}
+
+	resumed, err := NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.DeviceID() != id {
+		t.Errorf("resumed device id = %q, want the saved one", resumed.DeviceID())
+	}
+
+	fixed, err := NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store), WithLazyConnect(), WithDeviceID("custom"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.DeviceID() != "custom" {
+		t.Errorf("device id = %q, want custom", fixed.DeviceID())
+	}
+	if _, err = New(WithDeviceID("")); err == nil {
+		t.Error("expected error for empty device id")
+	}
+}
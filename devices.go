@@ -0,0 +1,184 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DeviceUpdate device fields to change with UpdateDevice, empty fields are left unchanged
+type DeviceUpdate struct {
+	Name string `json:"name,omitempty"`
+	// UserDeviceType device type, e.g. Dryer, see UserDeviceType in the device tags
+	UserDeviceType string `json:"user_device_type,omitempty"`
+	Make           string `json:"given_make,omitempty"`
+	Model          string `json:"given_model,omitempty"`
+	Location       string `json:"given_location,omitempty"`
+	Icon           string `json:"icon,omitempty"`
+}
+
+// devicePermissions what the editability tags of a device allow
+type devicePermissions struct {
+	editable  bool
+	mergeable bool
+	deletable bool
+	merged    bool
+}
+
+// UpdateDevice change the name, type, make, model, location or icon of a device
+// fails with ErrOperationNotAllowed if the device is not user editable
+func (s *SenseApi) UpdateDevice(ctx context.Context, deviceId string, upd DeviceUpdate) (d *DeviceDetail, err error) {
+	if upd == (DeviceUpdate{}) {
+		return d, errors.New("device update is empty")
+	}
+	perms, err := s.devicePermissions(ctx, deviceId)
+	if err != nil {
+		return d, err
+	}
+	if !perms.editable {
+		return d, fmt.Errorf("%w: edit device %s", ErrOperationNotAllowed, deviceId)
+	}
+	d = &DeviceDetail{}
+	err = s.deviceRequest(ctx, http.MethodPatch, "/"+url.PathEscape(deviceId), upd, d)
+	return d, err
+}
+
+// RenameDevice set the display name of a device
+func (s *SenseApi) RenameDevice(ctx context.Context, deviceId, name string) (d *DeviceDetail, err error) {
+	if name == "" {
+		return d, errors.New("device name must not be empty")
+	}
+	return s.UpdateDevice(ctx, deviceId, DeviceUpdate{Name: name})
+}
+
+// MergeDevices combine devices detected separately into one device called name
+// every device must be user mergeable
+func (s *SenseApi) MergeDevices(ctx context.Context, name string, deviceIds ...string) (d *DeviceDetail, err error) {
+	if len(deviceIds) < 2 {
+		return d, errors.New("merging needs at least two devices")
+	}
+	perms, err := s.devicesPermissions(ctx, deviceIds...)
+	if err != nil {
+		return d, err
+	}
+	for i, id := range deviceIds {
+		if !perms[i].mergeable {
+			return d, fmt.Errorf("%w: merge device %s", ErrOperationNotAllowed, id)
+		}
+	}
+	body := struct {
+		Name      string   `json:"name,omitempty"`
+		DeviceIds []string `json:"device_ids"`
+	}{name, deviceIds}
+	d = &DeviceDetail{}
+	err = s.deviceRequest(ctx, http.MethodPost, "/merge", body, d)
+	return d, err
+}
+
+// UnmergeDevice split a merged device back into the devices it was merged from
+func (s *SenseApi) UnmergeDevice(ctx context.Context, deviceId string) (err error) {
+	perms, err := s.devicePermissions(ctx, deviceId)
+	if err != nil {
+		return err
+	}
+	if !perms.merged {
+		return fmt.Errorf("%w: device %s is not merged", ErrOperationNotAllowed, deviceId)
+	}
+	return s.deviceRequest(ctx, http.MethodPost, "/"+url.PathEscape(deviceId)+"/unmerge", nil, nil)
+}
+
+// HideDevice hide a device from the device list and timeline or show it again
+func (s *SenseApi) HideDevice(ctx context.Context, deviceId string, hidden bool) (err error) {
+	perms, err := s.devicePermissions(ctx, deviceId)
+	if err != nil {
+		return err
+	}
+	if !perms.editable {
+		return fmt.Errorf("%w: hide device %s", ErrOperationNotAllowed, deviceId)
+	}
+	body := struct {
+		Hidden bool `json:"hidden"`
+	}{hidden}
+	return s.deviceRequest(ctx, http.MethodPatch, "/"+url.PathEscape(deviceId), body, nil)
+}
+
+// DeleteDevice delete a detected device, fails with ErrOperationNotAllowed unless it is user deletable
+func (s *SenseApi) DeleteDevice(ctx context.Context, deviceId string) (err error) {
+	perms, err := s.devicePermissions(ctx, deviceId)
+	if err != nil {
+		return err
+	}
+	if !perms.deletable {
+		return fmt.Errorf("%w: delete device %s", ErrOperationNotAllowed, deviceId)
+	}
+	return s.deviceRequest(ctx, http.MethodDelete, "/"+url.PathEscape(deviceId), nil, nil)
+}
+
+// devicePermissions look up the editability tags of deviceId in the devices overview
+func (s *SenseApi) devicePermissions(ctx context.Context, deviceId string) (p devicePermissions, err error) {
+	perms, err := s.devicesPermissions(ctx, deviceId)
+	if err != nil {
+		return p, err
+	}
+	return perms[0], err
+}
+
+// devicesPermissions look up the editability tags of every device id with a single devices overview
+// request, perms is in the order of deviceIds
+func (s *SenseApi) devicesPermissions(ctx context.Context, deviceIds ...string) (perms []devicePermissions, err error) {
+	for _, id := range deviceIds {
+		if id == "" {
+			return perms, errors.New("device id must not be empty")
+		}
+	}
+	do, err := s.DevicesOverviewContext(ctx, true)
+	if err != nil {
+		return perms, err
+	}
+	byId := make(map[string]devicePermissions, len(do.Devices))
+	for _, d := range do.Devices {
+		t := d.Tags
+		byId[d.Id] = devicePermissions{
+			editable:  t.UserEditable == "true",
+			mergeable: t.UserMergeable == "true",
+			deletable: t.UserDeletable == "true",
+			merged:    t.MergedDevices != "",
+		}
+	}
+	for _, id := range deviceIds {
+		p, ok := byId[id]
+		if !ok {
+			return perms, fmt.Errorf("%w: device %s", ErrNotFound, id)
+		}
+		perms = append(perms, p)
+	}
+	return perms, err
+}
+
+// deviceRequest send body as json to the devices endpoint of the monitor and parse the response into
+// parseType if not nil. cached device responses of the monitor are dropped
+func (s *SenseApi) deviceRequest(ctx context.Context, method, path string, body, parseType interface{}) (err error) {
+	var payload, contentType string
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload, contentType = string(b), jsonContentType
+	}
+	monitorId := s.getMonitorId(ctx)
+	u := fmt.Sprintf("%s/app/monitors/%s/devices%s", s.baseUrl, monitorId, path)
+	res, err := s.apiRequest(ctx, method, u, contentType, payload)
+	if err != nil {
+		return err
+	}
+	s.cache.invalidate(cacheScope{cacheDevices, monitorId})
+	if parseType == nil {
+		_, err = readRes(res)
+		return err
+	}
+	return parseRes(res, parseType)
+}
@@ -0,0 +1,120 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeviceEditing(t *testing.T) {
+	f := newFakeSense(t)
+	var mu sync.Mutex
+	var requests []string
+	record := func(r *http.Request) map[string]interface{} {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		return body
+	}
+	var overviews int32
+	f.mux.HandleFunc("/app/monitors/1/devices/overview", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&overviews, 1)
+		_, _ = w.Write([]byte(`{"devices":[
+			{"id":"dryer","name":"Dryer 1","tags":{"UserEditable":"true","name_useredit":"true","UserMergeable":"true","UserDeletable":"true"}},
+			{"id":"heat","name":"Heat","tags":{"UserEditable":"true","UserMergeable":"true"}},
+			{"id":"solar","name":"Solar","tags":{"UserEditable":"false"}},
+			{"id":"merged","name":"Both","tags":{"UserEditable":"true","MergedDevices":"a,b"}}]}`))
+	})
+	f.mux.HandleFunc("/app/monitors/1/devices/dryer", func(w http.ResponseWriter, r *http.Request) {
+		body := record(r)
+		if r.Method == http.MethodPatch {
+			if name, ok := body["name"]; ok {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"device": map[string]interface{}{"id": "dryer", "name": name}})
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+	f.mux.HandleFunc("/app/monitors/1/devices/merge", func(w http.ResponseWriter, r *http.Request) {
+		body := record(r)
+		if ids, _ := body["device_ids"].([]interface{}); len(ids) != 2 {
+			t.Errorf("merge body = %v", body)
+		}
+		_, _ = w.Write([]byte(`{"device":{"id":"dryer-heat","name":"Laundry"}}`))
+	})
+	f.mux.HandleFunc("/app/monitors/1/devices/merged/unmerge", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	d, err := s.RenameDevice(ctx, "dryer", "Dryer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Device.Name != "Dryer" {
+		t.Errorf("renamed device = %+v", d.Device)
+	}
+	if _, err = s.UpdateDevice(ctx, "dryer", DeviceUpdate{Make: "Bosch", Location: "Basement"}); err != nil {
+		t.Error(err)
+	}
+	if err = s.HideDevice(ctx, "dryer", true); err != nil {
+		t.Error(err)
+	}
+	if err = s.DeleteDevice(ctx, "dryer"); err != nil {
+		t.Error(err)
+	}
+	before := atomic.LoadInt32(&overviews)
+	if d, err = s.MergeDevices(ctx, "Laundry", "dryer", "heat"); err != nil || d.Device.Name != "Laundry" {
+		t.Errorf("merge = %+v, %v", d, err)
+	}
+	if n := atomic.LoadInt32(&overviews) - before; n != 1 {
+		t.Errorf("merge fetched the devices overview %d times, want 1", n)
+	}
+	if err = s.UnmergeDevice(ctx, "merged"); err != nil {
+		t.Error(err)
+	}
+
+	notAllowed := map[string]error{}
+	_, notAllowed["rename locked"] = s.RenameDevice(ctx, "solar", "Sun")
+	_, notAllowed["merge"] = s.MergeDevices(ctx, "", "dryer", "solar")
+	notAllowed["unmerge"] = s.UnmergeDevice(ctx, "dryer")
+	notAllowed["hide"] = s.HideDevice(ctx, "solar", true)
+	notAllowed["delete"] = s.DeleteDevice(ctx, "heat")
+	for op, err := range notAllowed {
+		if !errors.Is(err, ErrOperationNotAllowed) {
+			t.Errorf("%s err = %v, want ErrOperationNotAllowed", op, err)
+		}
+	}
+	if err = s.DeleteDevice(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing device err = %v, want ErrNotFound", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"PATCH /app/monitors/1/devices/dryer",
+		"PATCH /app/monitors/1/devices/dryer",
+		"PATCH /app/monitors/1/devices/dryer",
+		"DELETE /app/monitors/1/devices/dryer",
+		"POST /app/monitors/1/devices/merge",
+		"POST /app/monitors/1/devices/merged/unmerge",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %s, want %s", i, requests[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package sense
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrMFARequired       = errors.New("mfa required")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrNotFound          = errors.New("not found")
+	ErrServerUnavailable = errors.New("server unavailable")
+	// ErrRateLimitExceeded returned when the client side RateLimiter rejects a request
+	ErrRateLimitExceeded = errors.New("client rate limit exceeded")
+	// ErrUnrecognizedToken returned when the access token is not in the expected sense format
+	ErrUnrecognizedToken = errors.New("unrecognized access token")
+	// ErrNoSession returned by TokenStore.Load when no session has been saved
+	ErrNoSession = errors.New("no saved session")
+	// ErrLoggedOut returned by calls made after Logout
+	ErrLoggedOut = errors.New("logged out")
+	// ErrReloginFailed matched by a ReloginError
+	ErrReloginFailed = errors.New("re-login failed")
+	// ErrOperationNotAllowed returned when the device tags do not allow an edit
+	ErrOperationNotAllowed = errors.New("operation not allowed")
+)
+
+// APIError non 2xx response returned by the sense api
+// use errors.Is with ErrUnauthorized, ErrMFARequired, ErrRateLimited, ErrNotFound
+// or ErrServerUnavailable to check the error category
+type APIError struct {
+	StatusCode int
+	Endpoint   string
+	// Status sense status field, e.g. mfa_required
+	Status    string
+	Reason    string
+	RequestId string
+	Body      []byte
+	// Attempts number of requests made before giving up
+	Attempts int
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("sense api %s returned %d %s", e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrMFARequired:
+		return e.Status == errMfaRequired
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrServerUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+func newAPIError(res *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: res.StatusCode,
+		RequestId:  res.Header.Get("X-Request-Id"),
+		Body:       body,
+		Attempts:   1,
+	}
+	if e.RequestId == "" {
+		e.RequestId = res.Header.Get("X-Amzn-Requestid")
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		e.Endpoint = res.Request.URL.Path
+	}
+	r := struct {
+		Status      string `json:"status"`
+		ErrorReason string `json:"error_reason"`
+	}{}
+	if json.Unmarshal(body, &r) == nil {
+		e.Status = r.Status
+		e.Reason = r.ErrorReason
+	}
+	return e
+}
@@ -0,0 +1,51 @@
+package sense
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	f := newFakeSense(t)
+	status := http.StatusOK
+	f.mux.HandleFunc("/app/monitors/1/devices/always_on", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"status":"error","error_reason":"slow down"}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithRetryPolicy(NoRetry))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusBadGateway, ErrServerUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			status = tt.status
+			_, err := s.AlwaysOn()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %T, want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Reason != "slow down" || apiErr.RequestId != "req-1" ||
+				apiErr.Endpoint != "/app/monitors/1/devices/always_on" {
+				t.Errorf("unexpected api error %+v", apiErr)
+			}
+			if errors.Is(err, ErrMFARequired) {
+				t.Error("err should not match ErrMFARequired")
+			}
+		})
+	}
+}
@@ -2,13 +2,15 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/maodijim/sense-api"
 )
 
 func main() {
-	s, _ := sense.NewSenseApi("test@test.com", "test")
+	// prompt for the two factor code on stdin if the account has mfa enabled
+	s, _ := sense.NewSenseApi("test@test.com", "test", sense.WithMFAProvider(sense.PromptMFAProvider(os.Stdin, os.Stdout)))
 
 	// Get Time of Use Rate Zones
 	rz, _ := s.RateZone()
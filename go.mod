@@ -3,6 +3,8 @@ module github.com/maodijim/sense-api
 go 1.17
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/gorilla/websocket v1.4.2
+	gopkg.in/yaml.v3 v3.0.1
 )
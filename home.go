@@ -0,0 +1,130 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Enumerated home attributes, sense compares usage against homes with the same attributes.
+// values not listed here are passed through unchanged
+
+type HomeType string
+
+const (
+	HomeTypeSingleFamily HomeType = "SINGLE_FAMILY"
+	HomeTypeTownhouse    HomeType = "TOWNHOUSE"
+	HomeTypeApartment    HomeType = "APARTMENT"
+	HomeTypeCondo        HomeType = "CONDO"
+	HomeTypeMobileHome   HomeType = "MOBILE_HOME"
+)
+
+type HomeSizeType string
+
+const (
+	HomeSizeUnder1000  HomeSizeType = "SQFT_0_1000"
+	HomeSize1000To1500 HomeSizeType = "SQFT_1000_1500"
+	HomeSize1500To2000 HomeSizeType = "SQFT_1500_2000"
+	HomeSize2000To2500 HomeSizeType = "SQFT_2000_2500"
+	HomeSize2500To3000 HomeSizeType = "SQFT_2500_3000"
+	HomeSizeOver3000   HomeSizeType = "SQFT_3000_PLUS"
+)
+
+type YearBuiltType string
+
+const (
+	YearBuiltBefore1950 YearBuiltType = "BEFORE_1950"
+	YearBuilt1950s      YearBuiltType = "YEAR_1950_1959"
+	YearBuilt1960s      YearBuiltType = "YEAR_1960_1969"
+	YearBuilt1970s      YearBuiltType = "YEAR_1970_1979"
+	YearBuilt1980s      YearBuiltType = "YEAR_1980_1989"
+	YearBuilt1990s      YearBuiltType = "YEAR_1990_1999"
+	YearBuilt2000s      YearBuiltType = "YEAR_2000_2009"
+	YearBuiltAfter2010  YearBuiltType = "YEAR_2010_PLUS"
+)
+
+type OccupantsType string
+
+const (
+	OccupantsOne      OccupantsType = "ONE"
+	OccupantsTwo      OccupantsType = "TWO"
+	OccupantsThree    OccupantsType = "THREE"
+	OccupantsFour     OccupantsType = "FOUR"
+	OccupantsFivePlus OccupantsType = "FIVE_PLUS"
+)
+
+type OccupancyType string
+
+const (
+	OccupancyFullTime OccupancyType = "FULL_TIME"
+	OccupancyPartTime OccupancyType = "PART_TIME"
+	OccupancyVacation OccupancyType = "VACATION"
+)
+
+type BasementType string
+
+const (
+	BasementNone       BasementType = "NO_BASEMENT"
+	BasementFinished   BasementType = "FINISHED"
+	BasementUnfinished BasementType = "UNFINISHED"
+	BasementCrawlSpace BasementType = "CRAWL_SPACE"
+)
+
+// HomeAttributes description of the home a monitor is installed in
+type HomeAttributes struct {
+	Id                int           `json:"id"`
+	Name              string        `json:"name"`
+	State             string        `json:"state"`
+	PostalCode        string        `json:"postal_code"`
+	HomeType          HomeType      `json:"home_type"`
+	HomeSizeType      HomeSizeType  `json:"home_size_type"`
+	YearBuiltType     YearBuiltType `json:"year_built_type"`
+	NumberOfOccupants OccupantsType `json:"number_of_occupants"`
+	OccupancyType     OccupancyType `json:"occupancy_type"`
+	BasementType      BasementType  `json:"basement_type"`
+}
+
+// HomeAttributesPatch attributes to change with UpdateHomeAttributes, empty fields are left unchanged
+type HomeAttributesPatch struct {
+	Name              string        `json:"name,omitempty"`
+	PostalCode        string        `json:"postal_code,omitempty"`
+	HomeType          HomeType      `json:"home_type,omitempty"`
+	HomeSizeType      HomeSizeType  `json:"home_size_type,omitempty"`
+	YearBuiltType     YearBuiltType `json:"year_built_type,omitempty"`
+	NumberOfOccupants OccupantsType `json:"number_of_occupants,omitempty"`
+	OccupancyType     OccupancyType `json:"occupancy_type,omitempty"`
+	BasementType      BasementType  `json:"basement_type,omitempty"`
+}
+
+// HomeAttributes home type, size, age and occupancy sense uses to pick comparison cohorts
+func (s *SenseApi) HomeAttributes(ctx context.Context) (ha *HomeAttributes, err error) {
+	u := fmt.Sprintf("%s/app/monitors/%s/attributes", s.baseUrl, s.getMonitorId(ctx))
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return ha, err
+	}
+	ha = &HomeAttributes{}
+	err = parseRes(res, ha)
+	return ha, err
+}
+
+// UpdateHomeAttributes change the non empty fields of patch and return the updated attributes
+func (s *SenseApi) UpdateHomeAttributes(ctx context.Context, patch HomeAttributesPatch) (ha *HomeAttributes, err error) {
+	if patch == (HomeAttributesPatch{}) {
+		return ha, errors.New("home attributes patch is empty")
+	}
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return ha, err
+	}
+	u := fmt.Sprintf("%s/app/monitors/%s/attributes", s.baseUrl, s.getMonitorId(ctx))
+	res, err := s.apiRequest(ctx, http.MethodPatch, u, jsonContentType, string(b))
+	if err != nil {
+		return ha, err
+	}
+	ha = &HomeAttributes{}
+	err = parseRes(res, ha)
+	return ha, err
+}
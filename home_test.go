@@ -0,0 +1,49 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHomeAttributes(t *testing.T) {
+	f := newFakeSense(t)
+	var patched map[string]interface{}
+	f.mux.HandleFunc("/app/monitors/1/attributes", func(w http.ResponseWriter, r *http.Request) {
+		attrs := `{"id":1,"name":"Home","postal_code":"94107","home_type":"SINGLE_FAMILY","year_built_type":"YEAR_1990_1999"}`
+		if r.Method == http.MethodPatch {
+			if err := json.NewDecoder(r.Body).Decode(&patched); err != nil {
+				t.Error(err)
+			}
+			attrs = `{"id":1,"name":"Home","postal_code":"94107","home_type":"TOWNHOUSE","year_built_type":"YEAR_1990_1999"}`
+		}
+		_, _ = w.Write([]byte(attrs))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	ha, err := s.HomeAttributes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha.HomeType != HomeTypeSingleFamily || ha.YearBuiltType != YearBuilt1990s || ha.PostalCode != "94107" {
+		t.Errorf("attributes = %+v", ha)
+	}
+
+	ha, err = s.UpdateHomeAttributes(ctx, HomeAttributesPatch{HomeType: HomeTypeTownhouse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ha.HomeType != HomeTypeTownhouse {
+		t.Errorf("updated home type = %s", ha.HomeType)
+	}
+	if len(patched) != 1 || patched["home_type"] != "TOWNHOUSE" {
+		t.Errorf("patch body = %v, want only home_type", patched)
+	}
+	if _, err = s.UpdateHomeAttributes(ctx, HomeAttributesPatch{}); err == nil {
+		t.Error("expected error for empty patch")
+	}
+}
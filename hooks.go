@@ -0,0 +1,117 @@
+package sense
+
+import (
+	"time"
+)
+
+// AuthEventType kind of authentication lifecycle event
+type AuthEventType string
+
+const (
+	AuthEventLogin        AuthEventType = "login"
+	AuthEventMFAChallenge AuthEventType = "mfa_challenge"
+	AuthEventRenew        AuthEventType = "renew"
+	AuthEventFailure      AuthEventType = "auth_failure"
+	AuthEventLogout       AuthEventType = "logout"
+)
+
+// AuthEvent data passed to auth hooks, it never contains passwords or tokens
+type AuthEvent struct {
+	Type AuthEventType
+	// Op the step that failed for AuthEventFailure: AuthEventLogin, AuthEventMFAChallenge or AuthEventRenew
+	Op         AuthEventType
+	Time       time.Time
+	UserId     int
+	MonitorIds []int
+	// Expiry of the access token, zero if unknown
+	Expiry time.Time
+	// TokenErr ErrUnrecognizedToken if the expiry could not be read from the access token
+	TokenErr error
+	MFAType  string
+	// Relogin set on AuthEventLogin when the login replaced a session whose renewal was rejected,
+	// see WithCredentialProvider
+	Relogin bool
+	Err     error
+}
+
+type authHooks struct {
+	login        []func(AuthEvent)
+	mfaChallenge []func(AuthEvent)
+	renew        []func(AuthEvent)
+	failure      []func(AuthEvent)
+	logout       []func(AuthEvent)
+}
+
+// OnLogin register fn to be called after every successful login, including logins completed with mfa
+func (s *SenseApi) OnLogin(fn func(AuthEvent)) {
+	s.addHook(&s.hooks.login, fn)
+}
+
+// OnMFAChallenge register fn to be called when sense asks for a second factor
+func (s *SenseApi) OnMFAChallenge(fn func(AuthEvent)) {
+	s.addHook(&s.hooks.mfaChallenge, fn)
+}
+
+// OnRenew register fn to be called after every successful token renewal
+func (s *SenseApi) OnRenew(fn func(AuthEvent)) {
+	s.addHook(&s.hooks.renew, fn)
+}
+
+// OnAuthFailure register fn to be called when a login, mfa code or renewal fails, Op tells which
+func (s *SenseApi) OnAuthFailure(fn func(AuthEvent)) {
+	s.addHook(&s.hooks.failure, fn)
+}
+
+// OnLogout register fn to be called when the session is ended with Logout
+func (s *SenseApi) OnLogout(fn func(AuthEvent)) {
+	s.addHook(&s.hooks.logout, fn)
+}
+
+func (s *SenseApi) addHook(hooks *[]func(AuthEvent), fn func(AuthEvent)) {
+	s.authMutex.Lock()
+	defer s.authMutex.Unlock()
+	*hooks = append(*hooks, fn)
+}
+
+// emitAuthEvent call the hooks registered for ev.Type, filling in the current session details
+func (s *SenseApi) emitAuthEvent(ev AuthEvent) {
+	s.authMutex.RLock()
+	var hooks []func(AuthEvent)
+	switch ev.Type {
+	case AuthEventLogin:
+		hooks = s.hooks.login
+	case AuthEventMFAChallenge:
+		hooks = s.hooks.mfaChallenge
+	case AuthEventRenew:
+		hooks = s.hooks.renew
+	case AuthEventFailure:
+		hooks = s.hooks.failure
+	case AuthEventLogout:
+		hooks = s.hooks.logout
+	}
+	ev.UserId = s.authRes.UserId
+	for _, m := range s.authRes.Monitors {
+		ev.MonitorIds = append(ev.MonitorIds, m.Id)
+	}
+	token := s.authRes.AccessToken
+	s.authMutex.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	if token != "" {
+		info, err := parseToken(token)
+		ev.Expiry, ev.TokenErr = info.Expiry, err
+	}
+	for _, fn := range hooks {
+		fn(ev)
+	}
+}
+
+// authFailed emit an AuthEventFailure for op if err is set
+func (s *SenseApi) authFailed(op AuthEventType, err error) {
+	if err == nil {
+		return
+	}
+	s.emitAuthEvent(AuthEvent{Type: AuthEventFailure, Op: op, Err: err})
+}
@@ -0,0 +1,88 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuthHooks(t *testing.T) {
+	f := newFakeSense(t)
+	f.mfaCode = "123456"
+	s, err := New(f.options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var events []AuthEvent
+	record := func(ev AuthEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev)
+	}
+	s.OnLogin(record)
+	s.OnMFAChallenge(record)
+	s.OnRenew(record)
+	s.OnAuthFailure(record)
+
+	ctx := context.Background()
+	if _, err = s.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.CompleteMFA(ctx, "000000")
+	if err = s.CompleteMFA(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.RenewTokenContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []AuthEventType{AuthEventMFAChallenge, AuthEventFailure, AuthEventLogin, AuthEventRenew}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want types %v", events, want)
+	}
+	for i, ev := range events {
+		if ev.Type != want[i] {
+			t.Errorf("event %d type = %s, want %s", i, ev.Type, want[i])
+		}
+	}
+	if events[0].MFAType != "totp" {
+		t.Errorf("challenge mfa type = %q, want totp", events[0].MFAType)
+	}
+	if failure := events[1]; failure.Op != AuthEventMFAChallenge || !errors.Is(failure.Err, ErrUnauthorized) {
+		t.Errorf("failure event = %+v", failure)
+	}
+	login := events[2]
+	if login.UserId != 2 || len(login.MonitorIds) != 2 || login.MonitorIds[0] != 1 {
+		t.Errorf("login event = %+v", login)
+	}
+	if time.Until(login.Expiry) < 50*time.Minute {
+		t.Errorf("login expiry = %v, want about an hour from now", login.Expiry)
+	}
+}
+
+func TestAuthHooksRenewFailure(t *testing.T) {
+	f := newFakeSense(t)
+	s, err := NewSenseApiFromSession(Session{
+		AccessToken:  testToken(time.Now().Add(-time.Hour)),
+		RefreshToken: "revoked",
+		UserId:       2,
+	}, append(f.options(), WithLazyConnect(), WithRetryPolicy(NoRetry))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failure *AuthEvent
+	s.OnAuthFailure(func(ev AuthEvent) {
+		failure = &ev
+	})
+	if err = s.RenewToken(); err == nil {
+		t.Fatal("expected renew error")
+	}
+	if failure == nil || failure.Op != AuthEventRenew || !errors.Is(failure.Err, ErrUnauthorized) {
+		t.Errorf("failure event = %+v, want renew unauthorized", failure)
+	}
+}
@@ -0,0 +1,118 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Logout end the session: revoke it on the server where supported, close every realtime feed and
+// wipe the tokens from memory and the token store. local state is always wiped, a failed revoke
+// is returned for logging. an in flight renewal is waited for and no new one starts until the
+// session is wiped. afterwards calls fail with ErrLoggedOut until the next Login
+func (s *SenseApi) Logout(ctx context.Context) (err error) {
+	if s.isLoggedOut() {
+		return ErrLoggedOut
+	}
+	c := s.claimRenewal()
+	defer s.releaseRenewal(c)
+	// a concurrent Logout may have finished while we waited
+	if s.isLoggedOut() {
+		return ErrLoggedOut
+	}
+	revokeErr := s.revoke(ctx)
+	s.emitAuthEvent(AuthEvent{Type: AuthEventLogout, Err: revokeErr})
+
+	s.authMutex.Lock()
+	s.authRes = AuthRes{}
+	s.refreshToken = ""
+	s.pendingMfa = nil
+	s.loggedOut = true
+	s.authMutex.Unlock()
+
+	s.closeFeeds()
+	s.mutex.Lock()
+	s.messages = []RealTime{}
+	s.mutex.Unlock()
+	s.cache.clear()
+
+	if s.tokenStore != nil {
+		err = s.tokenStore.Clear()
+		if err != nil {
+			return fmt.Errorf("clear session: %w", err)
+		}
+	}
+	if revokeErr != nil {
+		return fmt.Errorf("revoke session: %w", revokeErr)
+	}
+	return err
+}
+
+// claimRenewal wait for the in flight renewal and take its place, renewals requested until
+// releaseRenewal wait for it and fail with ErrLoggedOut
+func (s *SenseApi) claimRenewal() (c *renewCall) {
+	s.renewMutex.Lock()
+	for s.renewing != nil {
+		prev := s.renewing
+		s.renewMutex.Unlock()
+		<-prev.done
+		s.renewMutex.Lock()
+	}
+	c = &renewCall{done: make(chan struct{}), err: ErrLoggedOut}
+	s.renewing = c
+	s.renewMutex.Unlock()
+	return c
+}
+
+func (s *SenseApi) releaseRenewal(c *renewCall) {
+	s.renewMutex.Lock()
+	s.renewing = nil
+	s.renewMutex.Unlock()
+	close(c.done)
+}
+
+// revoke ask sense to invalidate the tokens, a 404 means the server does not support it.
+// an expired access token is renewed first so the refresh token does not outlive the logout,
+// callers must hold the renewal claim
+func (s *SenseApi) revoke(ctx context.Context) (err error) {
+	token := s.accessToken()
+	if token == "" {
+		return err
+	}
+	if s.tokenExpired(token) {
+		err = s.renew(ctx)
+		if err != nil {
+			return fmt.Errorf("renew expired token, revoke skipped: %w", err)
+		}
+		token = s.accessToken()
+	}
+	res, err := s.send(ctx, http.MethodPost, s.baseUrl+"/logout", "", "", token)
+	if err != nil {
+		return err
+	}
+	_, err = readRes(res)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// closeFeeds close the default and every additional realtime feed
+func (s *SenseApi) closeFeeds() {
+	s.feedsMutex.Lock()
+	feeds := []*MonitorFeed{s.feed}
+	for f := range s.feeds {
+		feeds = append(feeds, f)
+	}
+	s.feedsMutex.Unlock()
+	for _, f := range feeds {
+		_ = f.Close()
+	}
+}
+
+func (s *SenseApi) isLoggedOut() bool {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.loggedOut
+}
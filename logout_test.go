@@ -0,0 +1,187 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestLogout(t *testing.T) {
+	f := newFakeSense(t)
+	var revoked int32
+	f.mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&revoked, 1)
+	})
+	store := NewMemoryTokenStore()
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	feed, err := s.ListenMonitor(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logout *AuthEvent
+	s.OnLogout(func(ev AuthEvent) {
+		logout = &ev
+	})
+
+	if err = s.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&revoked) != 1 {
+		t.Error("session was not revoked on the server")
+	}
+	if logout == nil || logout.UserId != 2 {
+		t.Errorf("logout event = %+v", logout)
+	}
+	if s.feed.conn() != nil || feed.conn() != nil {
+		t.Error("realtime feeds still open")
+	}
+	if sess := s.Session(); sess.AccessToken != "" || sess.RefreshToken != "" {
+		t.Error("tokens still in memory")
+	}
+	if _, err = store.Load(); !errors.Is(err, ErrNoSession) {
+		t.Errorf("store load err = %v, want ErrNoSession", err)
+	}
+	if _, err = s.RateZone(); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("RateZone err = %v, want ErrLoggedOut", err)
+	}
+	if _, err = s.ReadMessage(); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("ReadMessage err = %v, want ErrLoggedOut", err)
+	}
+	if _, err = feed.ReadMessage(ctx); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("feed ReadMessage err = %v, want ErrLoggedOut", err)
+	}
+	if err = s.RenewToken(); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("RenewToken err = %v, want ErrLoggedOut", err)
+	}
+	if err = s.Logout(ctx); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("second Logout err = %v, want ErrLoggedOut", err)
+	}
+
+	if _, err = s.Login(ctx, "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RateZone(); err != nil {
+		t.Errorf("RateZone after login: %v", err)
+	}
+}
+
+func TestLogoutExpiredToken(t *testing.T) {
+	f := newFakeSense(t)
+	expired := testToken(time.Now().Add(-time.Hour))
+	var revoked int32
+	f.mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "bearer "+expired {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		atomic.AddInt32(&revoked, 1)
+	})
+	sess := Session{AccessToken: expired, RefreshToken: "refresh", UserId: 2, Monitors: []Monitor{{Id: 1}}}
+	s, err := NewSenseApiFromSession(sess, append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&revoked) != 1 {
+		t.Error("session with an expired access token was not revoked on the server")
+	}
+
+	// the refresh token is rejected too, the revoke cannot be sent
+	sess.RefreshToken = "revoked"
+	s, err = NewSenseApiFromSession(sess, append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Logout(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Logout err = %v, want the failed renewal", err)
+	}
+	if !s.isLoggedOut() || s.Session().RefreshToken != "" {
+		t.Error("local session not wiped after a skipped revoke")
+	}
+	if n := atomic.LoadInt32(&revoked); n != 1 {
+		t.Errorf("revokes = %d, want 1", n)
+	}
+}
+
+func TestLogoutDuringDial(t *testing.T) {
+	f := newFakeSense(t)
+	dialing, release := make(chan struct{}), make(chan struct{})
+	f.mux.HandleFunc("/monitors/2/realtimefeed", func(w http.ResponseWriter, r *http.Request) {
+		close(dialing)
+		<-release
+		up := websocket.Upgrader{}
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			if _, _, err = c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := newMonitorFeed(s, 2)
+	connected := make(chan error, 1)
+	go func() {
+		connected <- feed.Connect(context.Background())
+	}()
+	<-dialing
+	if err = s.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err = <-connected; !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("Connect err = %v, want ErrLoggedOut", err)
+	}
+	if feed.conn() != nil {
+		t.Error("feed dialed during logout is still open")
+	}
+}
+
+func TestLogoutDuringRenewal(t *testing.T) {
+	f := newFakeSense(t)
+	f.renewDelay = 100 * time.Millisecond
+	store := NewMemoryTokenStore()
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewed := make(chan error, 1)
+	go func() {
+		renewed <- s.RenewToken()
+	}()
+	for f.renewCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err = s.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-renewed; err != nil {
+		t.Errorf("RenewToken err = %v", err)
+	}
+	if sess := s.Session(); sess.AccessToken != "" || sess.RefreshToken != "" {
+		t.Error("renewal wrote tokens back after logout")
+	}
+	if _, err = store.Load(); !errors.Is(err, ErrNoSession) {
+		t.Errorf("store load err = %v, want ErrNoSession", err)
+	}
+	if err = s.RenewToken(); !errors.Is(err, ErrLoggedOut) {
+		t.Errorf("RenewToken after logout err = %v, want ErrLoggedOut", err)
+	}
+}
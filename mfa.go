@@ -0,0 +1,136 @@
+package sense
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// MFAProvider supplies the second factor code when sense answers a login with mfa_required
+type MFAProvider interface {
+	MFACode(ctx context.Context, mfaType string) (code string, err error)
+}
+
+// MFAProviderFunc adapter to use a function as MFAProvider
+type MFAProviderFunc func(ctx context.Context, mfaType string) (code string, err error)
+
+func (f MFAProviderFunc) MFACode(ctx context.Context, mfaType string) (code string, err error) {
+	return f(ctx, mfaType)
+}
+
+// MFAChallenge pending second factor returned by Login when no MFAProvider is configured
+type MFAChallenge struct {
+	// Type mfa type requested by sense, e.g. totp
+	Type  string
+	token string
+	email string
+	// relogin the challenge belongs to a fallback login, see AuthEvent.Relogin
+	relogin bool
+}
+
+// WithMFAProvider answer mfa challenges during login with codes from p
+func WithMFAProvider(p MFAProvider) Option {
+	return func(s *SenseApi) error {
+		if p == nil {
+			return errors.New("mfa provider must not be nil")
+		}
+		s.mfaProvider = p
+		return nil
+	}
+}
+
+// CompleteMFA finish a login that returned an MFAChallenge with the code entered by the user
+func (s *SenseApi) CompleteMFA(ctx context.Context, code string) (err error) {
+	s.authMutex.Lock()
+	challenge := s.pendingMfa
+	s.authMutex.Unlock()
+	if challenge == nil {
+		return errors.New("no pending mfa challenge please run Login() first")
+	}
+	err = s.mfaAuth(ctx, challenge, code)
+	if err != nil {
+		s.authFailed(AuthEventMFAChallenge, err)
+		return err
+	}
+	s.authMutex.Lock()
+	if s.pendingMfa == challenge {
+		s.pendingMfa = nil
+	}
+	s.authMutex.Unlock()
+	return err
+}
+
+// TOTPProvider generate RFC 6238 codes from the shared secret shown when enabling two factor
+// authentication, for unattended daemons
+type TOTPProvider struct {
+	secret []byte
+}
+
+// NewTOTPProvider secret is the base32 encoded shared secret, spaces and case are ignored
+func NewTOTPProvider(secret string) (p *TOTPProvider, err error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return p, fmt.Errorf("invalid totp secret: %w", err)
+	}
+	if len(key) == 0 {
+		return p, errors.New("totp secret must not be empty")
+	}
+	return &TOTPProvider{secret: key}, err
+}
+
+func (p *TOTPProvider) MFACode(ctx context.Context, mfaType string) (code string, err error) {
+	if mfaType != "totp" {
+		return code, errors.New("totp provider can not answer mfa type: " + mfaType)
+	}
+	return totpCode(p.secret, time.Now()), err
+}
+
+// totpCode RFC 6238 code with HMAC-SHA1, 30 second period and 6 digits
+func totpCode(secret []byte, t time.Time) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(t.Unix()/totpPeriod))
+	mac := hmac.New(sha1.New, secret)
+	_, _ = mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// PromptMFAProvider write a prompt to w and read the code as one line from r, e.g. os.Stdin and os.Stdout
+// for interactive command line tools
+func PromptMFAProvider(r io.Reader, w io.Writer) MFAProvider {
+	scanner := bufio.NewScanner(r)
+	return MFAProviderFunc(func(ctx context.Context, mfaType string) (code string, err error) {
+		_, err = fmt.Fprintln(w, "Please enter two factor code: ")
+		if err != nil {
+			return code, err
+		}
+		if !scanner.Scan() {
+			if scanner.Err() != nil {
+				return code, scanner.Err()
+			}
+			return code, io.ErrUnexpectedEOF
+		}
+		return strings.TrimSpace(scanner.Text()), err
+	})
+}
@@ -0,0 +1,91 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+	// RFC 6238 appendix B SHA1 test vectors truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	tests := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range tests {
+		if got := totpCode(secret, time.Unix(unix, 0)); got != want {
+			t.Errorf("totpCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+	p, err := NewTOTPProvider("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.secret) != string(secret) {
+		t.Errorf("decoded secret = %q", p.secret)
+	}
+	if _, err = NewTOTPProvider("not base32!"); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	f := newFakeSense(t)
+	f.mfaCode = "123456"
+
+	_, err := NewSenseApi("user", "pass", f.options()...)
+	if !errors.Is(err, ErrMFARequired) {
+		t.Fatalf("err = %v, want ErrMFARequired", err)
+	}
+
+	provider := MFAProviderFunc(func(ctx context.Context, mfaType string) (string, error) {
+		return "123456", nil
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithMFAProvider(provider))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	s, err = New(f.options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	challenge, err := s.Login(ctx, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge == nil || challenge.Type != "totp" {
+		t.Fatalf("challenge = %+v, want totp challenge", challenge)
+	}
+	if err = s.CompleteMFA(ctx, "000000"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("wrong code err = %v, want ErrUnauthorized", err)
+	}
+	if err = s.CompleteMFA(ctx, "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if s.getMonitorId(context.Background()) != "1" {
+		t.Errorf("monitor id = %q, want 1", s.getMonitorId(context.Background()))
+	}
+	if err = s.CompleteMFA(ctx, "123456"); err == nil {
+		t.Error("expected error without pending challenge")
+	}
+}
+
+func TestPromptMFAProvider(t *testing.T) {
+	out := &strings.Builder{}
+	p := PromptMFAProvider(strings.NewReader(" 654321 \n"), out)
+	code, err := p.MFACode(context.Background(), "totp")
+	if err != nil || code != "654321" {
+		t.Errorf("code = %q, %v, want 654321", code, err)
+	}
+	if !strings.Contains(out.String(), "two factor code") {
+		t.Errorf("prompt = %q", out.String())
+	}
+}
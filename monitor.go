@@ -0,0 +1,97 @@
+package sense
+
+import (
+	"context"
+	"fmt"
+)
+
+type monitorCtxKey struct{}
+
+// MonitorContext bind REST calls made with the returned context to monitorId instead of the default monitor
+func MonitorContext(ctx context.Context, monitorId int) context.Context {
+	return context.WithValue(ctx, monitorCtxKey{}, monitorId)
+}
+
+// Monitors monitors of the account
+func (s *SenseApi) Monitors() []Monitor {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return append([]Monitor(nil), s.authRes.Monitors...)
+}
+
+// SetDefaultMonitor use monitorId for calls without MonitorContext and for the default realtime feed,
+// an open realtime feed switches monitor on its next reconnect
+func (s *SenseApi) SetDefaultMonitor(monitorId int) (err error) {
+	s.authMutex.Lock()
+	defer s.authMutex.Unlock()
+	for _, m := range s.authRes.Monitors {
+		if m.Id == monitorId {
+			s.defaultMonitorId = monitorId
+			return err
+		}
+	}
+	return fmt.Errorf("monitor %d not found in account", monitorId)
+}
+
+// WithDefaultMonitor use monitorId instead of the first monitor of the account for calls without
+// MonitorContext and for the default realtime feed
+func WithDefaultMonitor(monitorId int) Option {
+	return func(s *SenseApi) error {
+		if monitorId <= 0 {
+			return fmt.Errorf("invalid monitor id %d", monitorId)
+		}
+		s.defaultMonitorId = monitorId
+		return nil
+	}
+}
+
+// MonitorStatus connectivity, versions and device detection progress of a monitor
+type MonitorStatus struct {
+	MonitorId   int `json:"monitor_id"`
+	MonitorInfo struct {
+		Serial   string `json:"serial"`
+		Mac      string `json:"mac"`
+		Emac     string `json:"emac"`
+		Online   bool   `json:"online"`
+		Ethernet bool   `json:"ethernet"`
+		Ssid     string `json:"ssid"`
+		Signal   string `json:"signal"`
+		// WifiStrength received signal strength in dBm
+		WifiStrength    int    `json:"wifi_strength"`
+		NdtEnabled      bool   `json:"ndt_enabled"`
+		TestResult      string `json:"test_result"`
+		Version         string `json:"version"`
+		FirmwareVersion string `json:"firmware_version"`
+	} `json:"monitor_info"`
+	Signals struct {
+		Progress int    `json:"progress"`
+		Status   string `json:"status"`
+	} `json:"signals"`
+	DeviceDetection struct {
+		InProgress []struct {
+			Icon     string `json:"icon"`
+			Name     string `json:"name"`
+			Progress int    `json:"progress"`
+		} `json:"in_progress"`
+		Found []struct {
+			Icon     string `json:"icon"`
+			Name     string `json:"name"`
+			Progress int    `json:"progress"`
+		} `json:"found"`
+		NumDetected int `json:"num_detected"`
+	} `json:"device_detection"`
+	AuxIgnore bool   `json:"aux_ignore"`
+	AuxPort   string `json:"aux_port"`
+}
+
+// MonitorStatus connectivity, signal strength, versions and detection progress of the monitor
+func (s *SenseApi) MonitorStatus(ctx context.Context) (ms *MonitorStatus, err error) {
+	u := fmt.Sprintf("%s/app/monitors/%s/status", s.baseUrl, s.getMonitorId(ctx))
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return ms, err
+	}
+	ms = &MonitorStatus{}
+	err = parseRes(res, ms)
+	return ms, err
+}
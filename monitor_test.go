@@ -0,0 +1,32 @@
+package sense
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestMonitorStatus(t *testing.T) {
+	f := newFakeSense(t)
+	f.mux.HandleFunc("/app/monitors/2/status", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"monitor_id":2,"monitor_info":{"serial":"M123","online":true,"ethernet":false,
+			"ssid":"home","wifi_strength":-71,"version":"1.39.2","firmware_version":"2.4"},
+			"signals":{"progress":100,"status":"OK"},
+			"device_detection":{"in_progress":[{"name":"Possible Heat","progress":40}],"found":[],"num_detected":12}}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := s.MonitorStatus(MonitorContext(context.Background(), 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := ms.MonitorInfo
+	if ms.MonitorId != 2 || !info.Online || info.WifiStrength != -71 || info.Version != "1.39.2" || info.FirmwareVersion != "2.4" {
+		t.Errorf("unexpected monitor info %+v", info)
+	}
+	if ms.DeviceDetection.NumDetected != 12 || ms.DeviceDetection.InProgress[0].Progress != 40 {
+		t.Errorf("unexpected device detection %+v", ms.DeviceDetection)
+	}
+}
@@ -0,0 +1,49 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetNotificationSettings notification preferences of every monitor of the account keyed by monitor id
+func (s *SenseApi) GetNotificationSettings(ctx context.Context) (ns map[int]NotificationSettings, err error) {
+	u := fmt.Sprintf("%s/users/%d/settings", s.baseUrl, s.userId())
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return ns, err
+	}
+	us := &UserSettings{}
+	err = parseRes(res, us)
+	if err != nil {
+		return ns, err
+	}
+	ns = us.Settings.Notifications
+	if ns == nil {
+		ns = map[int]NotificationSettings{}
+	}
+	return ns, err
+}
+
+// UpdateNotificationSettings replace the notification preferences of monitorId,
+// read them with GetNotificationSettings first to toggle single settings
+func (s *SenseApi) UpdateNotificationSettings(ctx context.Context, monitorId int, ns NotificationSettings) (err error) {
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"notifications": map[string]NotificationSettings{strconv.Itoa(monitorId): ns},
+		},
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/users/%d/settings", s.baseUrl, s.userId())
+	res, err := s.apiRequest(ctx, http.MethodPatch, u, jsonContentType, string(b))
+	if err != nil {
+		return err
+	}
+	_, err = readRes(res)
+	return err
+}
@@ -0,0 +1,49 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestNotificationSettings(t *testing.T) {
+	f := newFakeSense(t)
+	var patched map[string]map[string]map[string]NotificationSettings
+	f.mux.HandleFunc("/users/2/settings", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			b, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(b, &patched); err != nil {
+				t.Error(err)
+			}
+			_, _ = w.Write(b)
+			return
+		}
+		_, _ = w.Write([]byte(`{"user_id":2,"settings":{"notifications":{
+			"1":{"monitor_offline_push":true,"new_peak_email":true},
+			"2":{"time_of_use":true}}}}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	ns, err := s.GetNotificationSettings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 2 || !ns[1].MonitorOfflinePush || !ns[1].NewPeakEmail || !ns[2].TimeOfUse {
+		t.Fatalf("unexpected settings %+v", ns)
+	}
+
+	n := ns[2]
+	n.AlwaysOnChangePush = true
+	if err = s.UpdateNotificationSettings(ctx, 2, n); err != nil {
+		t.Fatal(err)
+	}
+	got := patched["settings"]["notifications"]["2"]
+	if !got.AlwaysOnChangePush || !got.TimeOfUse || got.MonitorOfflinePush {
+		t.Errorf("patched settings = %+v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package sense
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Option configures a SenseApi created by NewSenseApi
+type Option func(s *SenseApi) error
+
+// WithHTTPClient use c for every REST request instead of a default http.Client
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *SenseApi) error {
+		if c == nil {
+			return errors.New("http client must not be nil")
+		}
+		s.httpClient = c
+		return nil
+	}
+}
+
+// WithBaseURL override the REST api base url, e.g. https://api.sense.com/apiservice/api/v1
+func WithBaseURL(baseUrl string) Option {
+	return func(s *SenseApi) error {
+		u, err := url.Parse(baseUrl)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.New("base url scheme must be http or https: " + baseUrl)
+		}
+		s.baseUrl = strings.TrimRight(u.String(), "/")
+		return nil
+	}
+}
+
+// WithRealtimeURL override the realtime websocket url, e.g. wss://clientrt.sense.com
+// the monitor realtime feed path is appended to the url path
+func WithRealtimeURL(realtimeUrl string) Option {
+	return func(s *SenseApi) error {
+		u, err := url.Parse(realtimeUrl)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "ws" && u.Scheme != "wss" {
+			return errors.New("realtime url scheme must be ws or wss: " + realtimeUrl)
+		}
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawQuery = ""
+		s.realtimeUrl = u
+		return nil
+	}
+}
+
+// WithWebsocketDialer use d to dial the realtime feed instead of websocket.DefaultDialer
+func WithWebsocketDialer(d *websocket.Dialer) Option {
+	return func(s *SenseApi) error {
+		if d == nil {
+			return errors.New("websocket dialer must not be nil")
+		}
+		s.wsDialer = d
+		return nil
+	}
+}
+
+// WithUserAgent send ua as User-Agent header on REST requests and the websocket handshake
+func WithUserAgent(ua string) Option {
+	return func(s *SenseApi) error {
+		s.userAgent = ua
+		return nil
+	}
+}
+
+// WithLazyConnect do not connect to the realtime feed during construction,
+// the feed is dialed by the first ReadMessage or Connect
+func WithLazyConnect() Option {
+	return func(s *SenseApi) error {
+		s.lazyConnect = true
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Endpoint keys accepted by RateLimiter.SetEndpointLimit
+// keys are api paths relative to the base url with numeric ids replaced by *
+const (
+	EndpointAuthenticate    = "authenticate"
+	EndpointRenew           = "renew"
+	EndpointTimeline        = "users/*/timeline"
+	EndpointAlwaysOn        = "app/monitors/*/devices/always_on"
+	EndpointDevicesOverview = "app/monitors/*/devices/overview"
+	EndpointRateZones       = "app/monitors/*/rate_zones"
+	EndpointMonitorStatus   = "app/monitors/*/status"
+	EndpointElectricityCost = "app/monitors/*/electricity_cost"
+	EndpointHomeAttributes  = "app/monitors/*/attributes"
+	EndpointTrends          = "app/history/trends"
+	EndpointComparisons     = "app/history/comparisons"
+)
+
+// RateLimitMode what a RateLimiter does when the budget is exhausted
+type RateLimitMode int
+
+const (
+	// RateLimitBlock wait until the budget allows the request or the context is done
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitFailFast return ErrRateLimitExceeded immediately
+	RateLimitFailFast
+)
+
+// RateLimiter token bucket limiter for REST requests
+// one RateLimiter can be shared by several SenseApi through WithRateLimiter,
+// it is safe for concurrent use
+type RateLimiter struct {
+	mode      RateLimitMode
+	mutex     sync.Mutex
+	global    *bucket
+	endpoints map[string]*bucket
+}
+
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allow rate requests per second on average with bursts of up to burst requests
+// a rate of 0 or below means the global budget is unlimited and only endpoint limits apply
+func NewRateLimiter(rate float64, burst int, mode RateLimitMode) *RateLimiter {
+	return &RateLimiter{
+		mode:      mode,
+		global:    newBucket(rate, burst),
+		endpoints: map[string]*bucket{},
+	}
+}
+
+// SetEndpointLimit budget requests to endpoint, e.g. EndpointTrends, in addition to the global budget
+func (l *RateLimiter) SetEndpointLimit(endpoint string, rate float64, burst int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.endpoints[endpoint] = newBucket(rate, burst)
+}
+
+// Wait take one token for endpoint from the global and endpoint budget
+func (l *RateLimiter) Wait(ctx context.Context, endpoint string) (err error) {
+	l.mutex.Lock()
+	now := time.Now()
+	buckets := []*bucket{l.global}
+	if b, ok := l.endpoints[endpoint]; ok {
+		buckets = append(buckets, b)
+	}
+	var wait time.Duration
+	for _, b := range buckets {
+		if w := b.waitTime(now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		if l.mode == RateLimitFailFast {
+			l.mutex.Unlock()
+			return ErrRateLimitExceeded
+		}
+		if dl, ok := ctx.Deadline(); ok && dl.Before(now.Add(wait)) {
+			l.mutex.Unlock()
+			return ErrRateLimitExceeded
+		}
+	}
+	// reserve the tokens now so concurrent callers queue up behind us
+	for _, b := range buckets {
+		b.take(1)
+	}
+	l.mutex.Unlock()
+	if wait == 0 {
+		return err
+	}
+	err = sleepContext(ctx, wait)
+	if err != nil {
+		l.mutex.Lock()
+		for _, b := range buckets {
+			b.take(-1)
+		}
+		l.mutex.Unlock()
+	}
+	return err
+}
+
+// WithRateLimiter throttle REST requests with l, pass the same limiter to several SenseApi to share the budget
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(s *SenseApi) error {
+		if l == nil {
+			return errors.New("rate limiter must not be nil")
+		}
+		s.rateLimiter = l
+		return nil
+	}
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// waitTime refill the bucket and return how long until one token is available
+func (b *bucket) waitTime(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take(n float64) {
+	if b.rate <= 0 {
+		return
+	}
+	b.tokens -= n
+}
+
+// endpointKey path of rawUrl relative to baseUrl with numeric path segments replaced by *
+func endpointKey(baseUrl, rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	p := u.Path
+	if b, err := url.Parse(baseUrl); err == nil {
+		p = strings.TrimPrefix(p, b.Path)
+	}
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, seg := range segments {
+		if seg != "" && strings.IndexFunc(seg, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+			segments[i] = "*"
+		}
+	}
+	return strings.Join(segments, "/")
+}
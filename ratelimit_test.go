@@ -0,0 +1,79 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEndpointKey(t *testing.T) {
+	base := "https://api.sense.com/apiservice/api/v1"
+	tests := map[string]string{
+		base + "/app/monitors/123/devices/overview?include_merged=true": EndpointDevicesOverview,
+		base + "/users/42/timeline?n_items=30":                          EndpointTimeline,
+		base + "/app/history/trends?monitor_id=1":                       EndpointTrends,
+		base + "/authenticate":                                          EndpointAuthenticate,
+	}
+	for u, want := range tests {
+		if got := endpointKey(base, u); got != want {
+			t.Errorf("endpointKey(%s) = %s, want %s", u, got, want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(0, 1, RateLimitFailFast)
+	l.SetEndpointLimit(EndpointTrends, 1, 2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, EndpointTrends); err != nil {
+			t.Fatalf("wait %d: %v", i, err)
+		}
+	}
+	if err := l.Wait(ctx, EndpointTrends); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("err = %v, want ErrRateLimitExceeded", err)
+	}
+	if err := l.Wait(ctx, EndpointTimeline); err != nil {
+		t.Errorf("unlimited endpoint err = %v", err)
+	}
+
+	l = NewRateLimiter(20, 1, RateLimitBlock)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, EndpointTrends); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s with burst 1 took %s, want at least 100ms", d)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(ctx, EndpointTrends); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context canceled", err)
+	}
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	f := newFakeSense(t)
+	l := NewRateLimiter(0, 1, RateLimitFailFast)
+	l.SetEndpointLimit(EndpointRateZones, 0.001, 1)
+	opts := append(f.options(), WithRateLimiter(l))
+	s1, err := NewSenseApi("user", "pass", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+	s2, err := NewSenseApi("user", "pass", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if _, err = s1.RateZone(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s2.RateZone(); !errors.Is(err, ErrRateLimitExceeded) {
+		t.Errorf("err = %v, want ErrRateLimitExceeded", err)
+	}
+}
@@ -0,0 +1,239 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MonitorFeed realtime feed connection to one monitor, every frame read is tagged with the monitor id
+// safe for concurrent use, reads are serialised
+type MonitorFeed struct {
+	s *SenseApi
+	// monitorId 0 follows the default monitor of s
+	monitorId int
+
+	// wsMutex guards ws and connectedId, dialMutex serialises dials and readMutex reads
+	wsMutex     sync.Mutex
+	ws          *websocket.Conn
+	connectedId int
+	dialMutex   sync.Mutex
+	readMutex   sync.Mutex
+}
+
+func newMonitorFeed(s *SenseApi, monitorId int) *MonitorFeed {
+	return &MonitorFeed{s: s, monitorId: monitorId}
+}
+
+// ListenMonitor connect to the realtime feed of monitorId in addition to the default feed
+// use Monitors to list the monitors of the account
+func (s *SenseApi) ListenMonitor(ctx context.Context, monitorId int) (f *MonitorFeed, err error) {
+	found := false
+	for _, m := range s.Monitors() {
+		if m.Id == monitorId {
+			found = true
+		}
+	}
+	if !found {
+		return f, fmt.Errorf("monitor %d not found in account", monitorId)
+	}
+	f = newMonitorFeed(s, monitorId)
+	err = f.Connect(ctx)
+	if err != nil {
+		return f, err
+	}
+	return f, err
+}
+
+// MonitorId id of the monitor the feed is connected to
+func (f *MonitorFeed) MonitorId() int {
+	if f.monitorId != 0 {
+		return f.monitorId
+	}
+	f.wsMutex.Lock()
+	id := f.connectedId
+	f.wsMutex.Unlock()
+	if id != 0 {
+		return id
+	}
+	id, _ = strconv.Atoi(f.s.getMonitorId(context.Background()))
+	return id
+}
+
+// Connect dial the feed if not already connected
+func (f *MonitorFeed) Connect(ctx context.Context) (err error) {
+	if f.s.isLoggedOut() {
+		return ErrLoggedOut
+	}
+	if f.conn() != nil {
+		return err
+	}
+	f.dialMutex.Lock()
+	defer f.dialMutex.Unlock()
+	// another goroutine may have connected while we waited
+	if f.conn() != nil {
+		return err
+	}
+	token := f.s.accessToken()
+	if token != "" && f.s.tokenExpired(token) {
+		err = f.s.renewToken(ctx, token)
+		if err != nil {
+			return err
+		}
+	}
+	return f.dial(ctx)
+}
+
+// listen replace the current connection with a new one
+func (f *MonitorFeed) listen(ctx context.Context) (err error) {
+	if f.s.isLoggedOut() {
+		return ErrLoggedOut
+	}
+	f.dialMutex.Lock()
+	defer f.dialMutex.Unlock()
+	return f.dial(ctx)
+}
+
+// dial replace the realtime connection, callers must hold dialMutex
+func (f *MonitorFeed) dial(ctx context.Context) (err error) {
+	s := f.s
+	monitorId := f.monitorId
+	if monitorId == 0 {
+		monitorId, _ = strconv.Atoi(s.getMonitorId(context.Background()))
+	}
+	q := url.Values{}
+	q.Add("access_token", s.accessToken())
+	q.Add("sense_protocol", senseProtocol)
+	q.Add("sense_client_type", "web")
+	q.Add("sense_device_id", s.clientDeviceId())
+	u := *s.realtimeUrl
+	u.Path = u.Path + "/monitors/" + strconv.Itoa(monitorId) + "/realtimefeed"
+	u.RawQuery = q.Encode()
+	header := http.Header{}
+	if s.userAgent != "" {
+		header.Set("User-Agent", s.userAgent)
+	}
+	ws, _, err := s.wsDialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		return err
+	}
+	f.wsMutex.Lock()
+	// Logout may have closed the feeds while dialing, the socket must not outlive the session
+	if s.isLoggedOut() {
+		f.wsMutex.Unlock()
+		_ = ws.Close()
+		return ErrLoggedOut
+	}
+	old := f.ws
+	f.ws = ws
+	f.connectedId = monitorId
+	s.trackFeed(f, true)
+	f.wsMutex.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	return err
+}
+
+func (f *MonitorFeed) conn() *websocket.Conn {
+	f.wsMutex.Lock()
+	defer f.wsMutex.Unlock()
+	return f.ws
+}
+
+// ReadMessage Read one real time message, connecting to the feed if needed
+// if ctx is done while waiting the websocket connection is closed and ctx.Err() returned,
+// the next read reconnects
+func (f *MonitorFeed) ReadMessage(ctx context.Context) (msg *RealTime, err error) {
+	msg = &RealTime{}
+	// gorilla websocket supports a single concurrent reader
+	f.readMutex.Lock()
+	defer f.readMutex.Unlock()
+	err = f.Connect(ctx)
+	if err != nil {
+		return msg, err
+	}
+	f.wsMutex.Lock()
+	ws, monitorId := f.ws, f.connectedId
+	f.wsMutex.Unlock()
+	if ws == nil {
+		return msg, errors.New("websocket closed")
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			// unblock the pending read
+			_ = ws.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	_, b, err := ws.ReadMessage()
+	close(stop)
+	<-done
+	if err != nil {
+		// the connection is unusable after a failed read, the next read reconnects
+		f.closeConn(ws)
+		if ctx.Err() != nil {
+			return msg, ctx.Err()
+		}
+		return msg, err
+	}
+	if ctx.Err() != nil {
+		_ = ws.SetReadDeadline(time.Time{})
+	}
+	err = json.Unmarshal(b, &msg)
+	if err != nil {
+		return msg, err
+	}
+	msg.MonitorId = monitorId
+	f.s.cache.observe(msg)
+	return msg, err
+}
+
+// Close websocket connection
+func (f *MonitorFeed) Close() (err error) {
+	f.wsMutex.Lock()
+	defer f.wsMutex.Unlock()
+	if f.ws == nil {
+		return errors.New("websocket already closed")
+	}
+	err = f.ws.Close()
+	f.ws = nil
+	f.s.trackFeed(f, false)
+	return err
+}
+
+// closeConn close ws if it is still the current connection
+func (f *MonitorFeed) closeConn(ws *websocket.Conn) {
+	f.wsMutex.Lock()
+	defer f.wsMutex.Unlock()
+	if f.ws == ws {
+		f.ws = nil
+	}
+	_ = ws.Close()
+}
+
+// trackFeed remember open feeds besides the default one so they can be closed together
+func (s *SenseApi) trackFeed(f *MonitorFeed, open bool) {
+	if f == s.feed {
+		return
+	}
+	s.feedsMutex.Lock()
+	defer s.feedsMutex.Unlock()
+	if open {
+		s.feeds[f] = struct{}{}
+	} else {
+		delete(s.feeds, f)
+	}
+}
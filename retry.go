@@ -0,0 +1,185 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed REST requests are retried
+type RetryPolicy struct {
+	// MaxAttempts total number of attempts including the first one, values below 2 disable retries
+	MaxAttempts int
+	// BaseDelay delay before the first retry, doubled on every further retry
+	BaseDelay time.Duration
+	// MaxDelay upper bound of a single delay, including delays requested by Retry-After
+	MaxDelay time.Duration
+	// Jitter fraction between 0 and 1 of each delay that is randomised
+	Jitter float64
+	// RetryStatusCodes response status codes that are retried
+	RetryStatusCodes []int
+	// RetryNetworkErrors retry transport errors such as timeouts, refused or reset connections
+	RetryNetworkErrors bool
+	// RetryNonIdempotent also retry methods other than GET and HEAD
+	RetryNonIdempotent bool
+}
+
+var (
+	// DefaultRetryPolicy policy used when WithRetryPolicy is not given
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+	}
+	// NoRetry disable retries
+	NoRetry = RetryPolicy{MaxAttempts: 1}
+)
+
+// WithRetryPolicy retry failed REST requests according to p
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *SenseApi) error {
+		if p.Jitter < 0 || p.Jitter > 1 {
+			return errors.New("retry jitter must be between 0 and 1")
+		}
+		s.retryPolicy = p
+		return nil
+	}
+}
+
+func (p RetryPolicy) allowsMethod(method string) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	switch method {
+	case "", http.MethodGet, http.MethodHead:
+		return true
+	}
+	return p.RetryNonIdempotent
+}
+
+func (p RetryPolicy) shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return p.RetryNetworkErrors && isRetryableNetErr(err)
+	}
+	for _, code := range p.RetryStatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay wait time before the next attempt, attempt is the number of the attempt that just failed
+func (p RetryPolicy) delay(attempt int, res *http.Response) (d time.Duration) {
+	if ra, ok := retryAfter(res); ok {
+		d = ra
+	} else {
+		// clamp in float64 before converting, large attempt numbers overflow time.Duration
+		f := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+		if p.MaxDelay > 0 && f > float64(p.MaxDelay) {
+			f = float64(p.MaxDelay)
+		}
+		if f < float64(math.MaxInt64) {
+			d = time.Duration(f)
+		} else {
+			d = math.MaxInt64
+		}
+		if p.Jitter > 0 {
+			d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// retryAfter parse the Retry-After header in either seconds or http date format
+func retryAfter(res *http.Response) (d time.Duration, ok bool) {
+	if res == nil {
+		return d, false
+	}
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return d, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return d, false
+}
+
+func isRetryableNetErr(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryResult turn the final response of a retried request into an error carrying the attempt count
+func retryResult(res *http.Response, err error, attempts int) (*http.Response, error) {
+	if attempts < 2 {
+		return res, err
+	}
+	if err != nil {
+		return res, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		apiErr := newAPIError(res, b)
+		apiErr.Attempts = attempts
+		return nil, apiErr
+	}
+	return res, err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package sense
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	f := newFakeSense(t)
+	var calls, failures int32
+	f.mux.HandleFunc("/app/monitors/1/devices/always_on", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= atomic.LoadInt32(&failures) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"info":"ok"}`))
+	})
+	policy := DefaultRetryPolicy
+	policy.BaseDelay = time.Millisecond
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithRetryPolicy(policy))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	atomic.StoreInt32(&failures, 2)
+	al, err := s.AlwaysOn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if al.Info != "ok" || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("info = %q calls = %d, want ok after 3 calls", al.Info, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&failures, 5)
+	_, err = s.AlwaysOn()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrServerUnavailable) {
+		t.Fatalf("err = %v, want server unavailable api error", err)
+	}
+	if apiErr.Attempts != 3 || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("attempts = %d calls = %d, want 3", apiErr.Attempts, calls)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if d := p.delay(attempt+1, nil); d != want {
+			t.Errorf("delay(%d) = %s, want %s", attempt+1, d, want)
+		}
+	}
+	for _, attempt := range []int{64, 100, 2000} {
+		if d := p.delay(attempt, nil); d != p.MaxDelay {
+			t.Errorf("delay(%d) = %s, want MaxDelay", attempt, d)
+		}
+		unbounded := RetryPolicy{BaseDelay: time.Second, Jitter: 0.2}
+		if d := unbounded.delay(attempt, nil); d <= 0 {
+			t.Errorf("unbounded delay(%d) = %s, want positive", attempt, d)
+		}
+	}
+	res := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	if d := p.delay(1, res); d != 3*time.Second {
+		t.Errorf("delay with Retry-After = %s, want 3s", d)
+	}
+	if p.allowsMethod(http.MethodPost) {
+		t.Error("POST should not be retried by default")
+	}
+}
@@ -1,14 +1,14 @@
 package sense
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/websocket"
 
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -21,10 +21,10 @@ import (
 const (
 	apiUrl               = "https://api.sense.com/apiservice/api/v1"
 	formContentType      = "application/x-www-form-urlencoded"
+	jsonContentType      = "application/json"
 	wssHost              = "clientrt.sense.com"
 	senseProtocol        = "8"
 	errMfaRequired       = "mfa_required"
-	deviceId             = "dnccvg0pzp62lmdotpg2hho0q7333ihzccpr6ifijc5lh9kigzc7m6jcovy8avq4wb2c40u0tsygwzi3vcj9lkmn0v7wjar36r5tvdqjqcfbtcrqaawfemmvmtamahg2"
 	defaultTimelineItems = 30
 )
 
@@ -32,34 +32,81 @@ var (
 	MaxMessageCache = 200
 )
 
-func (s *SenseApi) apiRequest(method, url, contentType, body string) (res *http.Response, err error) {
-	if s.authRes.AccessToken != "" && isTokenExpired(s.authRes.AccessToken) {
-		s.authRes.AccessToken = ""
-		err = s.RenewToken()
+// apiRequest send an authorized request, renewing the access token first if it expired.
+// tokens without a readable expiry are renewed once when sense rejects them
+func (s *SenseApi) apiRequest(ctx context.Context, method, url, contentType, body string) (res *http.Response, err error) {
+	if s.isLoggedOut() {
+		return res, ErrLoggedOut
+	}
+	token := s.accessToken()
+	if token != "" && s.tokenExpired(token) {
+		err = s.renewToken(ctx, token)
 		if err != nil {
-			return res, errors.New("token expired")
+			return res, fmt.Errorf("token expired: %w", err)
 		}
-		res, err = s.apiRequest(method, url, contentType, body)
+		token = s.accessToken()
+	}
+	res, err = s.send(ctx, method, url, contentType, body, token)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || token == "" {
 		return res, err
 	}
-	req, _ := http.NewRequest(method, url, strings.NewReader(body))
-	headers := req.Header
-	if contentType != "" {
-		headers.Add("Content-Type", contentType)
+	if _, parseErr := parseToken(token); parseErr == nil {
+		return res, err
 	}
-	headers.Add("x-sense-device-id", deviceId)
-	headers.Add("authorization", "bearer "+s.authRes.AccessToken)
-	client := http.Client{}
-	res, err = client.Do(req)
-	return res, err
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	_ = res.Body.Close()
+	err = s.renewToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized token rejected: %w", err)
+	}
+	return s.send(ctx, method, url, contentType, body, s.accessToken())
 }
 
-func (s *SenseApi) mfaAuth(mfaToken, totp string) (err error) {
-	u := apiUrl + "/authenticate/mfa"
+// send one request with token as bearer, retrying according to the retry policy
+func (s *SenseApi) send(ctx context.Context, method, url, contentType, body, token string) (res *http.Response, err error) {
+	retry := s.retryPolicy.allowsMethod(method)
+	for attempt := 1; ; attempt++ {
+		if s.rateLimiter != nil {
+			err = s.rateLimiter.Wait(ctx, endpointKey(s.baseUrl, url))
+			if err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+		if err != nil {
+			return res, err
+		}
+		headers := req.Header
+		if contentType != "" {
+			headers.Add("Content-Type", contentType)
+		}
+		headers.Add("x-sense-device-id", s.clientDeviceId())
+		headers.Add("authorization", "bearer "+token)
+		if s.userAgent != "" {
+			headers.Set("User-Agent", s.userAgent)
+		}
+		res, err = s.httpClient.Do(req)
+		if !retry || attempt >= s.retryPolicy.MaxAttempts || ctx.Err() != nil || !s.retryPolicy.shouldRetry(res, err) {
+			return retryResult(res, err, attempt)
+		}
+		delay := s.retryPolicy.delay(attempt, res)
+		if res != nil {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+		err = sleepContext(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *SenseApi) mfaAuth(ctx context.Context, challenge *MFAChallenge, totp string) (err error) {
+	u := s.baseUrl + "/authenticate/mfa"
 	v := url.Values{}
-	v.Add("mfaToken", mfaToken)
+	v.Add("mfaToken", challenge.token)
 	v.Add("totp", totp)
-	res, err := s.apiRequest(http.MethodPost, u, formContentType, v.Encode())
+	res, err := s.send(ctx, http.MethodPost, u, formContentType, v.Encode(), "")
 	if err != nil {
 		return err
 	}
@@ -68,90 +115,196 @@ func (s *SenseApi) mfaAuth(mfaToken, totp string) (err error) {
 	if err != nil {
 		return err
 	}
-	if authRes.Authorized {
-		s.authSet(authRes)
-	} else {
-		return errors.New(authRes.ErrorReason)
+	if !authRes.Authorized {
+		return fmt.Errorf("%w: %s", ErrUnauthorized, authRes.ErrorReason)
 	}
-	return err
+	s.authSet(authRes, challenge.email)
+	s.emitAuthEvent(AuthEvent{Type: AuthEventLogin, Relogin: challenge.relogin})
+	return s.saveSession()
 }
 
-func (s *SenseApi) authSet(a AuthRes) {
+// authSet replace the auth state with a, email is the login the tokens belong to
+func (s *SenseApi) authSet(a AuthRes, email string) {
+	s.authMutex.Lock()
+	defer s.authMutex.Unlock()
 	s.authRes = a
-	s.wssEndpoint = "monitors/" + s.getMonitorId() + "/realtimefeed"
+	s.email = email
+	s.loggedOut = false
+}
+
+func (s *SenseApi) accessToken() string {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.authRes.AccessToken
+}
+
+func (s *SenseApi) userId() int {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.authRes.UserId
 }
 
-func (s *SenseApi) authenticate(username, password string) (err error) {
-	authUrl := apiUrl + "/authenticate"
+// authenticate log in, failing with ErrMFARequired if sense asks for a code and no MFAProvider is configured
+// relogin marks the login events as a fallback for a rejected renewal
+func (s *SenseApi) authenticate(ctx context.Context, username, password string, relogin bool) (err error) {
+	challenge, err := s.login(ctx, username, password, relogin)
+	if err != nil {
+		return err
+	}
+	if challenge != nil {
+		return fmt.Errorf("%w: configure an MFAProvider with WithMFAProvider", ErrMFARequired)
+	}
+	return err
+}
+
+// Login authenticate with username and password
+// if sense requires a second factor and an MFAProvider is configured the code is requested from it,
+// otherwise the pending challenge is returned and the login is finished with CompleteMFA
+func (s *SenseApi) Login(ctx context.Context, username, password string) (challenge *MFAChallenge, err error) {
+	return s.login(ctx, username, password, false)
+}
+
+func (s *SenseApi) login(ctx context.Context, username, password string, relogin bool) (challenge *MFAChallenge, err error) {
+	op := AuthEventLogin
+	defer func() { s.authFailed(op, err) }()
+	authUrl := s.baseUrl + "/authenticate"
 	v := url.Values{}
 	v.Add("email", username)
 	v.Add("password", password)
-	res, err := s.apiRequest(http.MethodPost, authUrl, formContentType, v.Encode())
+	res, err := s.send(ctx, http.MethodPost, authUrl, formContentType, v.Encode(), "")
 	if err != nil {
-		return err
+		return challenge, err
 	}
 	authRes := AuthRes{}
 	err = parseRes(res, &authRes)
+	var apiErr *APIError
+	if errors.Is(err, ErrMFARequired) && errors.As(err, &apiErr) {
+		// sense answers mfa challenges with 401, the body carries the mfa token
+		err = json.Unmarshal(apiErr.Body, &authRes)
+	}
 	if err != nil {
-		return err
+		return challenge, err
 	}
 	if authRes.Authorized {
-		s.authSet(authRes)
-	} else if authRes.Status == errMfaRequired {
-		if authRes.MfaType != "totp" {
-			return errors.New("only support totp mfa type but received unsupported mfa type: " + authRes.MfaType)
-		}
-		var totp string
-		fmt.Println("Please enter two factor code: ")
-		fmt.Scanln(&totp)
-		err = s.mfaAuth(authRes.MfaToken, totp)
-		if err != nil {
-			return err
-		}
-	} else {
-		return errors.New(authRes.ErrorReason)
+		s.authSet(authRes, username)
+		s.emitAuthEvent(AuthEvent{Type: AuthEventLogin, Relogin: relogin})
+		return challenge, s.saveSession()
+	} else if authRes.Status != errMfaRequired {
+		return challenge, fmt.Errorf("%w: %s", ErrUnauthorized, authRes.ErrorReason)
+	}
+	if authRes.MfaType != "totp" {
+		return challenge, errors.New("only support totp mfa type but received unsupported mfa type: " + authRes.MfaType)
+	}
+	challenge = &MFAChallenge{Type: authRes.MfaType, token: authRes.MfaToken, email: username, relogin: relogin}
+	s.emitAuthEvent(AuthEvent{Type: AuthEventMFAChallenge, MFAType: challenge.Type})
+	op = AuthEventMFAChallenge
+	if s.mfaProvider == nil {
+		s.authMutex.Lock()
+		s.pendingMfa = challenge
+		s.authMutex.Unlock()
+		return challenge, err
+	}
+	code, err := s.mfaProvider.MFACode(ctx, challenge.Type)
+	if err != nil {
+		return nil, err
 	}
-	return err
+	return nil, s.mfaAuth(ctx, challenge, code)
+}
+
+// getMonitorId monitor bound to ctx with MonitorContext or the default monitor
+func (s *SenseApi) getMonitorId(ctx context.Context) string {
+	if id, ok := ctx.Value(monitorCtxKey{}).(int); ok {
+		return strconv.Itoa(id)
+	}
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.monitorId()
 }
 
-func (s *SenseApi) getMonitorId() string {
+// monitorId default monitor, callers must hold authMutex
+func (s *SenseApi) monitorId() string {
+	if s.defaultMonitorId != 0 {
+		return strconv.Itoa(s.defaultMonitorId)
+	}
 	if len(s.authRes.Monitors) == 0 {
 		return ""
 	}
 	return strconv.FormatInt(int64(s.authRes.Monitors[0].Id), 10)
 }
 
+// RenewToken renew the access token with the refresh token
 func (s *SenseApi) RenewToken() (err error) {
-	u := fmt.Sprintf("%s/renew", apiUrl)
+	return s.RenewTokenContext(context.Background())
+}
+
+// RenewTokenContext renew the access token with the refresh token
+// concurrent renewals are coalesced into a single request
+func (s *SenseApi) RenewTokenContext(ctx context.Context) (err error) {
+	return s.renewToken(ctx, "")
+}
+
+// renew send the renew request, use renewToken to coalesce concurrent renewals
+func (s *SenseApi) renew(ctx context.Context) (err error) {
+	defer func() { s.authFailed(AuthEventRenew, err) }()
+	s.authMutex.RLock()
+	token := s.authRes.AccessToken
 	v := url.Values{}
 	v.Add("refresh_token", s.authRes.RefreshToken)
 	v.Add("user_id", strconv.FormatInt(int64(s.authRes.UserId), 10))
 	v.Add("is_access_token", "true")
-	res, err := s.apiRequest(http.MethodPost, u, formContentType, v.Encode())
+	s.authMutex.RUnlock()
+	if token != "" && s.tokenExpired(token) {
+		token = ""
+	}
+	u := fmt.Sprintf("%s/renew", s.baseUrl)
+	res, err := s.send(ctx, http.MethodPost, u, formContentType, v.Encode(), token)
 	if err != nil {
 		return err
 	}
-	err = parseRes(res, &s.authRes)
+	renewed := AuthRes{}
+	err = parseRes(res, &renewed)
 	if err != nil {
 		return err
 	}
-	return err
+	s.authMutex.Lock()
+	if s.loggedOut {
+		// Logout ran while the request was in flight, keep the session wiped
+		s.authMutex.Unlock()
+		return ErrLoggedOut
+	}
+	if renewed.AccessToken != "" {
+		s.authRes.AccessToken = renewed.AccessToken
+	}
+	if renewed.RefreshToken != "" {
+		s.authRes.RefreshToken = renewed.RefreshToken
+	}
+	accessToken, refreshToken := s.authRes.AccessToken, s.authRes.RefreshToken
+	onRefreshed := s.onTokenRefreshed
+	s.authMutex.Unlock()
+	for _, fn := range onRefreshed {
+		fn(accessToken, refreshToken)
+	}
+	s.emitAuthEvent(AuthEvent{Type: AuthEventRenew})
+	return s.saveSession()
 }
 
+// ListenWss connect to the monitor realtime feed
 func (s *SenseApi) ListenWss() (err error) {
-	q := url.Values{}
-	q.Add("access_token", s.authRes.AccessToken)
-	q.Add("sense_protocol", senseProtocol)
-	q.Add("sense_client_type", "web")
-	q.Add("sense_device_id", deviceId)
-	u := url.URL{Scheme: "wss", Host: wssHost, Path: s.wssEndpoint, RawQuery: q.Encode()}
-	s.ws, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
-	return err
+	return s.ListenWssContext(context.Background())
+}
+
+// ListenWssContext connect to the monitor realtime feed, ctx bounds the websocket handshake
+func (s *SenseApi) ListenWssContext(ctx context.Context) (err error) {
+	return s.feed.listen(ctx)
 }
 
 func (s *SenseApi) AlwaysOn() (al *AlwaysOn, err error) {
-	u := fmt.Sprintf("%s/app/monitors/%s/devices/always_on", apiUrl, s.getMonitorId())
-	res, err := s.apiRequest("", u, formContentType, "")
+	return s.AlwaysOnContext(context.Background())
+}
+
+func (s *SenseApi) AlwaysOnContext(ctx context.Context) (al *AlwaysOn, err error) {
+	u := fmt.Sprintf("%s/app/monitors/%s/devices/always_on", s.baseUrl, s.getMonitorId(ctx))
+	res, err := s.apiRequest(ctx, "", u, formContentType, "")
 	if err != nil {
 		return al, err
 	}
@@ -160,46 +313,57 @@ func (s *SenseApi) AlwaysOn() (al *AlwaysOn, err error) {
 	return al, err
 }
 
-func (s *SenseApi) DevicesOverview(includeMerged bool) (do *DevicesOverview, err error) {
-	u := fmt.Sprintf("%s/app/monitors/%s/devices/overview?include_merged=%t", apiUrl, s.getMonitorId(), includeMerged)
-	res, err := s.apiRequest("", u, "", "")
+// Device usage, runtime and state of a single device, deviceId as listed by DevicesOverview
+// never cached, the state and usage change without changing the device checksum
+func (s *SenseApi) Device(ctx context.Context, deviceId string) (d *DeviceDetail, err error) {
+	if deviceId == "" {
+		return d, errors.New("device id must not be empty")
+	}
+	u := fmt.Sprintf("%s/app/monitors/%s/devices/%s", s.baseUrl, s.getMonitorId(ctx), url.PathEscape(deviceId))
+	res, err := s.apiRequest(ctx, "", u, formContentType, "")
 	if err != nil {
-		return do, err
+		return d, err
 	}
+	d = &DeviceDetail{}
+	err = parseRes(res, d)
+	return d, err
+}
+
+func (s *SenseApi) DevicesOverview(includeMerged bool) (do *DevicesOverview, err error) {
+	return s.DevicesOverviewContext(context.Background(), includeMerged)
+}
+
+func (s *SenseApi) DevicesOverviewContext(ctx context.Context, includeMerged bool) (do *DevicesOverview, err error) {
+	monitorId := s.getMonitorId(ctx)
+	u := fmt.Sprintf("%s/app/monitors/%s/devices/overview?include_merged=%t", s.baseUrl, monitorId, includeMerged)
 	do = &DevicesOverview{}
-	err = parseRes(res, do)
+	err = s.cachedGet(ctx, cacheDevices, monitorId, u, do)
 	if err != nil {
 		return do, err
 	}
+	s.cache.setChecksum(cacheScope{cacheDevices, monitorId}, do.DeviceDataChecksum)
 	return do, err
 }
 
 func (s *SenseApi) TimeLine(items int) (tl *TimeLineRes, err error) {
-	if items <= 0 {
-		items = defaultTimelineItems
-	}
-	u := fmt.Sprintf("%s/users/%d/timeline?n_items=%d", apiUrl, s.authRes.UserId, items)
-	res, err := s.apiRequest("", u, "", "")
-	if err != nil {
-		return tl, err
-	}
-	tl = &TimeLineRes{}
-	err = parseRes(res, tl)
-	if err != nil {
-		return tl, err
-	}
-	return tl, err
+	return s.TimeLineContext(context.Background(), items)
+}
+
+func (s *SenseApi) TimeLineContext(ctx context.Context, items int) (tl *TimeLineRes, err error) {
+	return s.timelinePage(ctx, TimelineQuery{Items: items})
 }
 
 // RateZone Time of Use Rate Zones
 func (s *SenseApi) RateZone() (rz *RateZones, err error) {
-	u := fmt.Sprintf("%s/app/monitors/%s/rate_zones", apiUrl, s.getMonitorId())
-	res, err := s.apiRequest("", u, "", "")
-	if err != nil {
-		return rz, err
-	}
+	return s.RateZoneContext(context.Background())
+}
+
+// RateZoneContext Time of Use Rate Zones
+func (s *SenseApi) RateZoneContext(ctx context.Context) (rz *RateZones, err error) {
+	monitorId := s.getMonitorId(ctx)
+	u := fmt.Sprintf("%s/app/monitors/%s/rate_zones", s.baseUrl, monitorId)
 	rz = &RateZones{}
-	err = parseRes(res, rz)
+	err = s.cachedGet(ctx, cacheMonitor, monitorId, u, rz)
 	if err != nil {
 		return rz, err
 	}
@@ -216,13 +380,17 @@ const (
 )
 
 func (s *SenseApi) Trend(scale TrendScale, start time.Time) (trend *TrendType, err error) {
+	return s.TrendContext(context.Background(), scale, start)
+}
+
+func (s *SenseApi) TrendContext(ctx context.Context, scale TrendScale, start time.Time) (trend *TrendType, err error) {
 	v := url.Values{}
-	v.Add("monitor_id", s.getMonitorId())
+	v.Add("monitor_id", s.getMonitorId(ctx))
 	v.Add("device_id", "")
 	v.Add("scale", string(scale))
 	v.Add("start", start.Format(time.RFC3339))
-	u := fmt.Sprintf("%s/app/history/trends?%s", apiUrl, v.Encode())
-	res, err := s.apiRequest("", u, "", "")
+	u := fmt.Sprintf("%s/app/history/trends?%s", s.baseUrl, v.Encode())
+	res, err := s.apiRequest(ctx, "", u, "", "")
 	if err != nil {
 		return trend, err
 	}
@@ -234,46 +402,47 @@ func (s *SenseApi) Trend(scale TrendScale, start time.Time) (trend *TrendType, e
 	return trend, err
 }
 
-func (s *SenseApi) reconnect() (err error) {
-	if s.ws == nil {
-		if s.authRes.AccessToken != "" && isTokenExpired(s.authRes.AccessToken) {
-			s.authRes.AccessToken = ""
-			err = s.RenewToken()
-			if err != nil {
-				return err
-			}
-			err = s.ListenWss()
-			if err != nil {
-				return err
-			}
-		} else {
-			err = s.ListenWss()
-			if err != nil {
-				return err
-			}
+func (s *SenseApi) setReadingAsync(reading bool) {
+	s.mutex.Lock()
+	s.readingAsync = reading
+	s.mutex.Unlock()
+}
+
+// ReadMessageAsync read message async and store messages in cache
+// use ReadMessages() to retrieve cached messages
+func (s *SenseApi) ReadMessageAsync(close <-chan bool) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-close:
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+	err = s.ReadMessageAsyncContext(ctx)
+	if ctx.Err() != nil {
+		return nil
 	}
 	return err
 }
 
-// ReadMessageAsync read message async and store messages in cache
+// ReadMessageAsyncContext read message async and store messages in cache until ctx is done
 // use ReadMessages() to retrieve cached messages
-func (s *SenseApi) ReadMessageAsync(close <-chan bool) (err error) {
-	s.readingAsync = true
+func (s *SenseApi) ReadMessageAsyncContext(ctx context.Context) (err error) {
+	s.setReadingAsync(true)
+	defer s.setReadingAsync(false)
 	for {
 		select {
-		case <-close:
-			s.readingAsync = false
-			return
+		case <-ctx.Done():
+			return ctx.Err()
 		default:
-			err = s.reconnect()
+			err = s.Connect(ctx)
 			if err != nil {
-				s.readingAsync = false
 				return err
 			}
-			rt, err := s.ReadMessage()
+			rt, err := s.ReadMessageContext(ctx)
 			if err != nil {
-				s.readingAsync = false
 				return err
 			}
 			s.mutex.Lock()
@@ -289,10 +458,19 @@ func (s *SenseApi) ReadMessageAsync(close <-chan bool) (err error) {
 // ReadMessages Read Cached messages
 // Cached messages are created async by ReadMessageAsync()
 func (s *SenseApi) ReadMessages() (msgs []RealTime, err error) {
-	if !s.readingAsync {
+	return s.ReadMessagesContext(context.Background())
+}
+
+// ReadMessagesContext Read Cached messages
+// Cached messages are created async by ReadMessageAsync()
+func (s *SenseApi) ReadMessagesContext(ctx context.Context) (msgs []RealTime, err error) {
+	s.mutex.RLock()
+	reading := s.readingAsync
+	s.mutex.RUnlock()
+	if !reading {
 		return msgs, errors.New("reading async not start please run ReadMessageAsync() to start async reader")
 	}
-	err = s.reconnect()
+	err = s.Connect(ctx)
 	if err != nil {
 		return msgs, err
 	}
@@ -305,40 +483,30 @@ func (s *SenseApi) ReadMessages() (msgs []RealTime, err error) {
 
 // ReadMessage Read one real time message
 func (s *SenseApi) ReadMessage() (msg *RealTime, err error) {
-	msg = &RealTime{}
-	err = s.reconnect()
-	if err != nil {
-		return msg, err
-	}
-	_, b, err := s.ws.ReadMessage()
-	if err != nil {
-		return msg, err
-	}
-	err = json.Unmarshal(b, &msg)
-	if err != nil {
-		return msg, err
-	}
-	return msg, err
+	return s.ReadMessageContext(context.Background())
 }
 
-// Close websocket connection
+// ReadMessageContext Read one real time message, connecting to the realtime feed if needed
+// if ctx is done while waiting the websocket connection is closed and ctx.Err() returned,
+// the next read reconnects
+func (s *SenseApi) ReadMessageContext(ctx context.Context) (msg *RealTime, err error) {
+	return s.feed.ReadMessage(ctx)
+}
+
+// Close websocket connection of the default monitor feed
 func (s *SenseApi) Close() (err error) {
-	if s.ws == nil {
-		return errors.New("websocket already closed")
-	}
-	err = s.ws.Close()
-	if err != nil {
-		return err
-	}
-	s.ws = nil
-	return err
+	return s.feed.Close()
 }
 
 func (s *SenseApi) GetHistoryComparison() (hc HistoryCompare, err error) {
+	return s.GetHistoryComparisonContext(context.Background())
+}
+
+func (s *SenseApi) GetHistoryComparisonContext(ctx context.Context) (hc HistoryCompare, err error) {
 	v := url.Values{}
-	v.Add("monitor_id", s.getMonitorId())
-	u := fmt.Sprintf("%s/app/history/comparisons?%s", apiUrl, v.Encode())
-	res, err := s.apiRequest("", u, "", "")
+	v.Add("monitor_id", s.getMonitorId(ctx))
+	u := fmt.Sprintf("%s/app/history/comparisons?%s", s.baseUrl, v.Encode())
+	res, err := s.apiRequest(ctx, "", u, "", "")
 	if err != nil {
 		return hc, err
 	}
@@ -349,40 +517,14 @@ func (s *SenseApi) GetHistoryComparison() (hc HistoryCompare, err error) {
 	return hc, err
 }
 
-func unmarshallJWT(token string) (result *jwtClaims) {
-	t, _, err := new(jwt.Parser).ParseUnverified(token, &jwtClaims{})
-	if err != nil {
-		log.Printf("failed to parse jwt token: %s", err)
-	}
-	if claims, ok := t.Claims.(*jwtClaims); ok {
-		return claims
-	}
-	return result
-}
-
-func isTokenExpired(token string) (ok bool) {
-	ok = true
-	tPart := strings.Split(token, ".")
-	if len(tPart) != 5 {
-		return ok
-	}
-	j := unmarshallJWT(strings.Join(tPart[2:], "."))
-	exp := j.Exp
-	if time.Since(time.Unix(int64(exp), 0)) < 0 {
-		return false
-	}
-	return ok
-}
-
 func parseRes(res *http.Response, parseType interface{}) (err error) {
 	if reflect.TypeOf(parseType).Kind() != reflect.Ptr {
 		return errors.New("parseType must be pointer reference")
 	}
-	b, err := ioutil.ReadAll(res.Body)
+	b, err := readRes(res)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
 	err = json.Unmarshal(b, parseType)
 	if err != nil {
 		return err
@@ -390,14 +532,76 @@ func parseRes(res *http.Response, parseType interface{}) (err error) {
 	return err
 }
 
-func NewSenseApi(username, password string) (s *SenseApi, err error) {
+// readRes read and close the response body, non 2xx responses are returned as *APIError
+func readRes(res *http.Response) (b []byte, err error) {
+	defer res.Body.Close()
+	b, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return b, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return b, newAPIError(res, b)
+	}
+	return b, err
+}
+
+// New create a client without logging in, authenticate with Login and CompleteMFA
+func New(opts ...Option) (s *SenseApi, err error) {
 	s = &SenseApi{
-		messages: []RealTime{},
+		messages:    []RealTime{},
+		feeds:       map[*MonitorFeed]struct{}{},
+		httpClient:  &http.Client{},
+		baseUrl:     apiUrl,
+		realtimeUrl: &url.URL{Scheme: "wss", Host: wssHost},
+		wsDialer:    websocket.DefaultDialer,
+		retryPolicy: DefaultRetryPolicy,
+		clockSkew:   defaultClockSkew,
+	}
+	s.feed = newMonitorFeed(s, 0)
+	for _, opt := range opts {
+		err = opt(s)
+		if err != nil {
+			return s, err
+		}
+	}
+	if s.deviceId == "" {
+		s.deviceId, err = newDeviceId()
+	}
+	return s, err
+}
+
+// NewSenseApi authenticate with username and password and connect to the realtime feed
+// opts can be used to override the http client, api urls, websocket dialer and user agent.
+// with WithTokenStore a saved session of username is resumed instead of logging in.
+// failing to connect to the realtime feed is not an error, the feed is dialed again by ReadMessage.
+// use WithLazyConnect to skip the realtime feed until it is first read
+func NewSenseApi(username, password string, opts ...Option) (s *SenseApi, err error) {
+	return NewSenseApiContext(context.Background(), username, password, opts...)
+}
+
+// NewSenseApiContext same as NewSenseApi, ctx bounds the login and the realtime feed handshake
+func NewSenseApiContext(ctx context.Context, username, password string, opts ...Option) (s *SenseApi, err error) {
+	s, err = New(opts...)
+	if err != nil {
+		return s, err
 	}
-	err = s.authenticate(username, password)
+	err = s.loginOrResume(ctx, username, password)
 	if err != nil {
 		return s, err
 	}
-	err = s.ListenWss()
+	s.connectEagerly(ctx)
 	return s, err
 }
+
+// connectEagerly dial the realtime feed unless lazy connect is enabled, a failed dial does not make
+// the REST client unusable and is retried by the next ReadMessage
+func (s *SenseApi) connectEagerly(ctx context.Context) {
+	if !s.lazyConnect {
+		_ = s.Connect(ctx)
+	}
+}
+
+// Connect dial the realtime feed of the default monitor if not already connected
+func (s *SenseApi) Connect(ctx context.Context) (err error) {
+	return s.feed.Connect(ctx)
+}
@@ -1,8 +1,19 @@
 package sense
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/websocket"
 )
 
 func TestNewSenseApi(t *testing.T) {
@@ -36,3 +47,367 @@ func TestNewSenseApi(t *testing.T) {
 		})
 	}
 }
+
+func testToken(exp time.Time) string {
+	t, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwtClaims{
+		Iss:       "sense",
+		Exp:       int(exp.Unix()),
+		UserId:    2,
+		AccountId: 3,
+		Roles:     "user",
+	}).SignedString([]byte("secret"))
+	return "t1.2." + t
+}
+
+type fakeSense struct {
+	*httptest.Server
+	mux       *http.ServeMux
+	mu        sync.Mutex
+	userAgent string
+	// deviceIds device ids seen in REST headers and realtime queries
+	deviceIds map[string]bool
+	renewals  int
+	// renewDelay how long /renew takes to answer
+	renewDelay time.Duration
+	// tokenTTL lifetime of access tokens issued by /authenticate
+	tokenTTL time.Duration
+	// mfaCode if set /authenticate requires this totp code
+	mfaCode string
+}
+
+// newFakeSense start a local stand-in for the sense api with one monitor
+func newFakeSense(t *testing.T) *fakeSense {
+	f := &fakeSense{mux: http.NewServeMux(), tokenTTL: time.Hour, deviceIds: map[string]bool{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.userAgent = r.UserAgent()
+		for _, id := range []string{r.Header.Get("x-sense-device-id"), r.URL.Query().Get("sense_device_id")} {
+			if id != "" {
+				f.deviceIds[id] = true
+			}
+		}
+		f.mu.Unlock()
+		f.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	authorized := func(w http.ResponseWriter) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"authorized":    true,
+			"user_id":       2,
+			"account_id":    3,
+			"access_token":  testToken(time.Now().Add(f.ttl())),
+			"refresh_token": "refresh",
+			"monitors":      []map[string]interface{}{{"id": 1}, {"id": 2}},
+		})
+	}
+	f.mux.HandleFunc("/authenticate", func(w http.ResponseWriter, r *http.Request) {
+		if f.mfaCode != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":"mfa_required","mfa_token":"mfa-token","mfa_type":"totp"}`))
+			return
+		}
+		authorized(w)
+	})
+	f.mux.HandleFunc("/authenticate/mfa", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("mfaToken") != "mfa-token" || r.FormValue("totp") != f.mfaCode {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":"error","error_reason":"invalid code"}`))
+			return
+		}
+		authorized(w)
+	})
+	f.mux.HandleFunc("/renew", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		f.renewals++
+		delay := f.renewDelay
+		f.mu.Unlock()
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"authorized":    true,
+			"access_token":  testToken(time.Now().Add(time.Hour)),
+			"refresh_token": "refresh",
+		})
+	})
+	f.mux.HandleFunc("/app/monitors/1/rate_zones", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"present":[{"id":7,"name":"peak"}]}`))
+	})
+	f.mux.HandleFunc("/monitors/1/realtimefeed", func(w http.ResponseWriter, r *http.Request) {
+		up := websocket.Upgrader{}
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			err = c.WriteMessage(websocket.TextMessage, []byte(`{"type":"realtime_update","payload":{"w":100}}`))
+			if err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	return f
+}
+
+func (f *fakeSense) ttl() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokenTTL
+}
+
+func (f *fakeSense) renewCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.renewals
+}
+
+func (f *fakeSense) options() []Option {
+	return []Option{
+		WithBaseURL(f.URL),
+		WithRealtimeURL("ws" + strings.TrimPrefix(f.URL, "http")),
+	}
+}
+
+func TestNewSenseApiOptions(t *testing.T) {
+	f := newFakeSense(t)
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithUserAgent("sense-test"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	rz, err := s.RateZone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rz.Present) != 1 || rz.Present[0].Name != "peak" {
+		t.Errorf("unexpected rate zones %+v", rz)
+	}
+	f.mu.Lock()
+	ua := f.userAgent
+	f.mu.Unlock()
+	if ua != "sense-test" {
+		t.Errorf("user agent = %q, want sense-test", ua)
+	}
+	msg, err := s.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != PayloadRealTimeUpdate || msg.Payload.W != 100 {
+		t.Errorf("unexpected message %v", msg)
+	}
+	_, err = NewSenseApi("user", "pass", WithBaseURL("ftp://example.com"))
+	if err == nil {
+		t.Error("expected error for invalid base url scheme")
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	f := newFakeSense(t)
+	block := make(chan struct{})
+	defer close(block)
+	f.mux.HandleFunc("/users/2/timeline", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	})
+	f.mux.HandleFunc("/monitors/2/realtimefeed", func(w http.ResponseWriter, r *http.Request) {
+		up := websocket.Upgrader{}
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		<-block
+	})
+	s, err := NewSenseApi("user", "pass", f.options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.TimeLineContext(ctx, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TimeLineContext err = %v, want deadline exceeded", err)
+	}
+
+	feed, err := s.ListenMonitor(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = feed.ReadMessage(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadMessageContext err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	f := newFakeSense(t)
+	f.tokenTTL = -time.Minute
+	s, err := NewSenseApi("user", "pass", f.options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = s.ReadMessageAsyncContext(ctx)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if _, err := s.RateZone(); err != nil {
+					t.Error(err)
+				}
+				if _, err := s.ReadMessage(); err != nil && !strings.Contains(err.Error(), "closed") {
+					t.Error(err)
+				}
+				_, _ = s.ReadMessages()
+				_ = s.RenewToken()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(20 * time.Millisecond)
+		_ = s.Close()
+	}()
+	wg.Wait()
+	if f.renewCount() == 0 {
+		t.Error("expected the expired token to be renewed")
+	}
+}
+
+func TestRealtimeDialFailure(t *testing.T) {
+	f := newFakeSense(t)
+	s, err := NewSenseApi("user", "pass", WithBaseURL(f.URL), WithRealtimeURL("ws://127.0.0.1:1"))
+	if err != nil {
+		t.Fatalf("construction failed because of the realtime feed: %v", err)
+	}
+	if _, err = s.RateZone(); err != nil {
+		t.Error(err)
+	}
+	if _, err = s.ReadMessage(); err == nil {
+		t.Error("expected realtime dial error from ReadMessage")
+	}
+
+	s, err = NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.feed.conn() != nil {
+		t.Error("lazy client connected during construction")
+	}
+	if _, err = s.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMultiMonitor(t *testing.T) {
+	f := newFakeSense(t)
+	f.mux.HandleFunc("/app/monitors/2/rate_zones", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"present":[{"id":8,"name":"off-peak"}]}`))
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := s.Monitors(); len(m) != 2 || m[0].Id != 1 || m[1].Id != 2 {
+		t.Fatalf("monitors = %+v", m)
+	}
+
+	rz, err := s.RateZoneContext(MonitorContext(context.Background(), 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rz.Present[0].Id != 8 {
+		t.Errorf("bound rate zone id = %d, want 8", rz.Present[0].Id)
+	}
+	if rz, _ = s.RateZone(); rz.Present[0].Id != 7 {
+		t.Errorf("default rate zone id = %d, want 7", rz.Present[0].Id)
+	}
+	if err = s.SetDefaultMonitor(9); err == nil {
+		t.Error("expected error for unknown monitor")
+	}
+	if err = s.SetDefaultMonitor(2); err != nil {
+		t.Fatal(err)
+	}
+	if rz, _ = s.RateZone(); rz.Present[0].Id != 8 {
+		t.Errorf("default rate zone id after SetDefaultMonitor = %d, want 8", rz.Present[0].Id)
+	}
+
+	if _, err = s.ListenMonitor(context.Background(), 9); err == nil {
+		t.Error("expected error for unknown monitor")
+	}
+	feed, err := s.ListenMonitor(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	msg, err := feed.ReadMessage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.MonitorId != 1 || feed.MonitorId() != 1 {
+		t.Errorf("message monitor = %d, feed monitor = %d, want 1", msg.MonitorId, feed.MonitorId())
+	}
+}
+
+func TestDevice(t *testing.T) {
+	f := newFakeSense(t)
+	state, runs := "off", 3
+	f.mux.HandleFunc("/app/monitors/1/devices/abc123", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"device":{"id":"abc123","name":"Dryer","tags":{"DateFirstUsage":"2021-05-30","UserEditable":"true"}},
+			"last_state":%q,"last_state_time":"2021-06-01T10:00:00Z","notes":null,
+			"usage":{"avg_watts":2800,"avg_duration":3600,"avg_monthly_runs":12.5,"current_month_runs":%d,
+				"comparison":{"title":"Dryer","w":120,"cohort":{"state":"CA"}}}}`, state, runs)
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect(), WithResponseCache(0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.Device(context.Background(), "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Device.Name != "Dryer" || d.Device.Tags.DateFirstUsage != "2021-05-30" || d.LastState != "off" || d.LastStateTime.IsZero() {
+		t.Errorf("device = %+v", d)
+	}
+	if d.Usage.AvgDuration != 3600 || d.Usage.CurrentMonthRuns != 3 || d.Usage.Comparison.Cohort.State != "CA" {
+		t.Errorf("usage = %+v", d.Usage)
+	}
+
+	// the detail is live, a cached copy would keep reporting the old state
+	f.mu.Lock()
+	state, runs = "on", 4
+	f.mu.Unlock()
+	if d, err = s.Device(context.Background(), "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if d.LastState != "on" || d.Usage.CurrentMonthRuns != 4 {
+		t.Errorf("second call = %s/%d, want on/4", d.LastState, d.Usage.CurrentMonthRuns)
+	}
+	if _, err = s.Device(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
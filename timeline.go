@@ -0,0 +1,187 @@
+package sense
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TimelineQuery page and filter of a timeline request
+type TimelineQuery struct {
+	// Items page size, defaults to 30
+	Items int
+	// Before only return events older than Before, use the time of the last item to get the next page
+	Before time.Time
+	// After only return events newer than After
+	After time.Time
+	// DeviceId only return events of this device
+	DeviceId string
+	// Types only return events of these types, e.g. DeviceWasOn
+	Types []string
+}
+
+// match whether item passes the device and type filters of q
+func (q *TimelineQuery) match(item *TimelineItem) bool {
+	if q.DeviceId != "" && item.DeviceId != q.DeviceId {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if item.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeLinePage one page of timeline events selected by q, MoreItems reports whether older events exist
+// device and type filters are applied to the fetched page, so a filtered page may hold fewer than
+// q.Items events while more matching events exist
+func (s *SenseApi) TimeLinePage(ctx context.Context, q TimelineQuery) (tl *TimeLineRes, err error) {
+	tl, err = s.timelinePage(ctx, q)
+	if err != nil {
+		return tl, err
+	}
+	items := tl.Items[:0]
+	for i := range tl.Items {
+		if q.match(&tl.Items[i]) {
+			items = append(items, tl.Items[i])
+		}
+	}
+	tl.Items = items
+	return tl, err
+}
+
+// timelinePage fetch a page of q without applying the filters
+func (s *SenseApi) timelinePage(ctx context.Context, q TimelineQuery) (tl *TimeLineRes, err error) {
+	if q.Items <= 0 {
+		q.Items = defaultTimelineItems
+	}
+	v := url.Values{}
+	v.Add("n_items", strconv.Itoa(q.Items))
+	if !q.Before.IsZero() {
+		v.Add("before", q.Before.UTC().Format(time.RFC3339Nano))
+	}
+	if !q.After.IsZero() {
+		v.Add("after", q.After.UTC().Format(time.RFC3339Nano))
+	}
+	if q.DeviceId != "" {
+		v.Add("device_id", q.DeviceId)
+	}
+	u := fmt.Sprintf("%s/users/%d/timeline?%s", s.baseUrl, s.userId(), v.Encode())
+	res, err := s.apiRequest(ctx, "", u, "", "")
+	if err != nil {
+		return tl, err
+	}
+	tl = &TimeLineRes{}
+	err = parseRes(res, tl)
+	return tl, err
+}
+
+// TimelineIterator walks the timeline from newest to oldest fetching pages as needed
+//
+//	it := s.IterateTimeline(ctx, sense.TimelineQuery{Types: []string{"DeviceWasOn"}}, since)
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type TimelineIterator struct {
+	s     *SenseApi
+	ctx   context.Context
+	q     TimelineQuery
+	until time.Time
+	page  []TimelineItem
+	item  TimelineItem
+	more  bool
+	err   error
+	// seen events at the cursor time, pages overlap there because the cursor is inclusive
+	seen map[string]bool
+}
+
+// IterateTimeline iterate over the events matching q from q.Before, or now, back to until
+// a zero until walks the whole timeline. q.After is ignored, use until instead
+func (s *SenseApi) IterateTimeline(ctx context.Context, q TimelineQuery, until time.Time) *TimelineIterator {
+	q.After = time.Time{}
+	return &TimelineIterator{s: s, ctx: ctx, q: q, until: until, more: true, seen: map[string]bool{}}
+}
+
+// Next advance to the next event, false when the timeline is exhausted, until is reached or a page failed
+func (it *TimelineIterator) Next() bool {
+	for it.err == nil {
+		for len(it.page) > 0 {
+			item := it.page[0]
+			it.page = it.page[1:]
+			if !it.until.IsZero() && item.Time.Before(it.until) {
+				it.page, it.more = nil, false
+				return false
+			}
+			if it.q.match(&item) {
+				it.item = item
+				return true
+			}
+		}
+		if !it.more {
+			return false
+		}
+		tl, err := it.s.timelinePage(it.ctx, it.q)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(tl.Items) == 0 {
+			return false
+		}
+		it.more = tl.MoreItems
+		last := tl.Items[len(tl.Items)-1].Time
+		seen := it.seen
+		if !last.Equal(it.q.Before.Add(-time.Nanosecond)) {
+			seen = map[string]bool{}
+		}
+		it.page = make([]TimelineItem, 0, len(tl.Items))
+		for _, item := range tl.Items {
+			key := timelineKey(&item)
+			if it.seen[key] {
+				continue
+			}
+			if item.Time.Equal(last) {
+				seen[key] = true
+			}
+			it.page = append(it.page, item)
+		}
+		it.seen = seen
+		if len(it.page) == 0 {
+			// every event of the page shares the cursor time and was returned already, skip past it
+			if it.q.Before.Equal(last) {
+				// the cursor did not move, stop instead of fetching the same page forever
+				it.more = false
+			}
+			it.q.Before = last
+			continue
+		}
+		// before is exclusive, ask for events up to and including the last one so events sharing
+		// its timestamp but cut off by the page size are not skipped
+		it.q.Before = last.Add(time.Nanosecond)
+	}
+	return false
+}
+
+// timelineKey identify an event to drop the overlap between pages
+func timelineKey(item *TimelineItem) string {
+	return item.Time.UTC().Format(time.RFC3339Nano) + "|" + item.DeviceId + "|" + item.Type
+}
+
+// Item current event, valid after Next returned true
+func (it *TimelineIterator) Item() TimelineItem {
+	return it.item
+}
+
+// Err first error encountered while fetching pages
+func (it *TimelineIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,135 @@
+package sense
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimelinePaging(t *testing.T) {
+	f := newFakeSense(t)
+	base := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	var all []TimelineItem
+	for i := 0; i < 7; i++ {
+		typ := "DeviceWasOn"
+		if i%2 == 1 {
+			typ = "DeviceWasOff"
+		}
+		all = append(all, TimelineItem{Time: base.Add(-time.Duration(i) * time.Hour), Type: typ, DeviceId: "dryer"})
+	}
+	var pages int32
+	f.mux.HandleFunc("/users/2/timeline", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pages, 1)
+		n, _ := strconv.Atoi(r.FormValue("n_items"))
+		var items []TimelineItem
+		for _, item := range all {
+			if before := r.FormValue("before"); before != "" {
+				b, err := time.Parse(time.RFC3339Nano, before)
+				if err != nil {
+					t.Errorf("before = %q: %v", before, err)
+				}
+				if !item.Time.Before(b) {
+					continue
+				}
+			}
+			items = append(items, item)
+		}
+		more := len(items) > n
+		if more {
+			items = items[:n]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "more_items": more})
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tl, err := s.TimeLinePage(ctx, TimelineQuery{Items: 3, Before: base.Add(-time.Hour), Types: []string{"DeviceWasOn"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tl.MoreItems || len(tl.Items) != 2 || !tl.Items[1].Time.Equal(base.Add(-4*time.Hour)) {
+		t.Errorf("page = %+v", tl)
+	}
+
+	it := s.IterateTimeline(ctx, TimelineQuery{Items: 3}, time.Time{})
+	var got []TimelineItem
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if len(got) != len(all) || !got[6].Time.Equal(all[6].Time) {
+		t.Errorf("iterated %d items, want %d", len(got), len(all))
+	}
+	if n := atomic.LoadInt32(&pages); n != 4 {
+		t.Errorf("fetched %d pages, want 4", n)
+	}
+
+	it = s.IterateTimeline(ctx, TimelineQuery{Items: 2, Types: []string{"DeviceWasOff"}}, base.Add(-4*time.Hour))
+	got = got[:0]
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	if len(got) != 2 || got[0].Type != "DeviceWasOff" || !got[1].Time.Equal(base.Add(-3*time.Hour)) {
+		t.Errorf("filtered items = %+v", got)
+	}
+
+	if tl, err = s.TimeLine(30); err != nil || len(tl.Items) != len(all) {
+		t.Errorf("TimeLine = %d items, %v", len(tl.Items), err)
+	}
+}
+
+func TestTimelineIteratorSameTime(t *testing.T) {
+	f := newFakeSense(t)
+	base := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	all := []TimelineItem{
+		{Time: base, Type: "DeviceWasOn", DeviceId: "dryer"},
+		{Time: base.Add(-time.Hour), Type: "DeviceWasOn", DeviceId: "kettle"},
+		{Time: base.Add(-time.Hour), Type: "DeviceWasOff", DeviceId: "dryer"},
+		{Time: base.Add(-2 * time.Hour), Type: "DeviceWasOff", DeviceId: "kettle"},
+	}
+	f.mux.HandleFunc("/users/2/timeline", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.FormValue("n_items"))
+		var items []TimelineItem
+		for _, item := range all {
+			if b, err := time.Parse(time.RFC3339Nano, r.FormValue("before")); err == nil && !item.Time.Before(b) {
+				continue
+			}
+			items = append(items, item)
+		}
+		more := len(items) > n
+		if more {
+			items = items[:n]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "more_items": more})
+	})
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the two events an hour before base are split by the page boundary
+	it := s.IterateTimeline(context.Background(), TimelineQuery{Items: 2}, time.Time{})
+	var got []TimelineItem
+	for it.Next() {
+		got = append(got, it.Item())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if len(got) != len(all) {
+		t.Fatalf("iterated %+v, want %d items", got, len(all))
+	}
+	for i := range all {
+		if timelineKey(&got[i]) != timelineKey(&all[i]) {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], all[i])
+		}
+	}
+}
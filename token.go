@@ -0,0 +1,188 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	defaultClockSkew = 10 * time.Second
+	// refresherRetryDelay wait time of the background refresher after a failed renewal
+	refresherRetryDelay = 30 * time.Second
+	// renewTimeout bound of a shared renewal when the http client has no timeout
+	renewTimeout = time.Minute
+)
+
+type renewCall struct {
+	done chan struct{}
+	err  error
+}
+
+// renewToken renew the access token unless another goroutine is already doing it, in which case
+// wait for that renewal instead. if stale is not empty and the access token no longer equals stale
+// the token has been renewed in the meantime and no request is made.
+// the renewal runs detached from every caller so one cancelled caller does not fail the others,
+// ctx only bounds how long this caller waits for it
+func (s *SenseApi) renewToken(ctx context.Context, stale string) (err error) {
+	if s.isLoggedOut() {
+		return ErrLoggedOut
+	}
+	s.renewMutex.Lock()
+	c := s.renewing
+	if c == nil {
+		if stale != "" && s.accessToken() != stale {
+			s.renewMutex.Unlock()
+			return err
+		}
+		c = &renewCall{done: make(chan struct{})}
+		s.renewing = c
+		go s.runRenewal(c)
+	}
+	s.renewMutex.Unlock()
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runRenewal perform the renewal of c bounded by the http client timeout
+func (s *SenseApi) runRenewal(c *renewCall) {
+	timeout := s.httpClient.Timeout
+	if timeout <= 0 {
+		timeout = renewTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c.err = s.renewOrRelogin(ctx)
+
+	s.renewMutex.Lock()
+	s.renewing = nil
+	s.renewMutex.Unlock()
+	close(c.done)
+}
+
+// OnTokenRefreshed register fn to be called with the new token pair after every successful renewal,
+// e.g. to persist the tokens. fn must not block
+func (s *SenseApi) OnTokenRefreshed(fn func(accessToken, refreshToken string)) {
+	s.authMutex.Lock()
+	defer s.authMutex.Unlock()
+	s.onTokenRefreshed = append(s.onTokenRefreshed, fn)
+}
+
+// StartTokenRefresher renew the access token in the background margin before it expires
+// until ctx is done or the session is logged out. failed renewals are retried every 30 seconds.
+// tokens without a readable expiry are left to apiRequest, which renews them once sense rejects them
+func (s *SenseApi) StartTokenRefresher(ctx context.Context, margin time.Duration) {
+	go s.refreshTokens(ctx, margin, refresherRetryDelay)
+}
+
+// refreshTokens loop of StartTokenRefresher, retryDelay is the wait after a failed renewal and
+// between checks of an unrecognized token
+func (s *SenseApi) refreshTokens(ctx context.Context, margin, retryDelay time.Duration) {
+	for {
+		if s.isLoggedOut() {
+			return
+		}
+		token := s.accessToken()
+		info, err := parseToken(token)
+		if err != nil {
+			// park until a login replaces the token with one carrying an expiry
+			if sleepContext(ctx, retryDelay) != nil {
+				return
+			}
+			continue
+		}
+		if wait := time.Until(info.Expiry) - margin; wait > 0 {
+			if sleepContext(ctx, wait) != nil {
+				return
+			}
+			// the token may have been renewed or replaced in the meantime
+			continue
+		}
+		err = s.renewToken(ctx, token)
+		if ctx.Err() != nil || errors.Is(err, ErrLoggedOut) {
+			return
+		}
+		// do not spin when sense issues tokens shorter lived than margin
+		wait := time.Second
+		if err != nil {
+			wait = retryDelay
+		}
+		if sleepContext(ctx, wait) != nil {
+			return
+		}
+	}
+}
+
+// TokenInfo claims of a sense access token
+type TokenInfo struct {
+	Expiry    time.Time
+	UserId    int
+	AccountId int
+	Roles     string
+	Issuer    string
+}
+
+// Expired whether the token expires within skew from now
+func (t TokenInfo) Expired(skew time.Duration) bool {
+	return !time.Now().Add(skew).Before(t.Expiry)
+}
+
+// TokenInfo parse the claims of the current access token
+// returns ErrUnrecognizedToken if the token is not in the expected sense format
+func (s *SenseApi) TokenInfo() (info TokenInfo, err error) {
+	return parseToken(s.accessToken())
+}
+
+// WithClockSkew renew access tokens skew before they expire to allow for clock differences
+// between this host and sense, defaults to 10 seconds
+func WithClockSkew(skew time.Duration) Option {
+	return func(s *SenseApi) error {
+		if skew < 0 {
+			return errors.New("clock skew must not be negative")
+		}
+		s.clockSkew = skew
+		return nil
+	}
+}
+
+// tokenExpired whether token needs renewing, unrecognised tokens are renewed by apiRequest once
+// the server rejects them
+func (s *SenseApi) tokenExpired(token string) bool {
+	info, err := parseToken(token)
+	if err != nil {
+		return false
+	}
+	return info.Expired(s.clockSkew)
+}
+
+// parseToken sense access tokens are two dot separated prefix parts followed by a jwt
+func parseToken(token string) (info TokenInfo, err error) {
+	tPart := strings.Split(token, ".")
+	if len(tPart) != 5 {
+		return info, fmt.Errorf("%w: expected 5 dot separated parts but got %d", ErrUnrecognizedToken, len(tPart))
+	}
+	claims := &jwtClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(strings.Join(tPart[2:], "."), claims)
+	if err != nil {
+		return info, fmt.Errorf("%w: %s", ErrUnrecognizedToken, err)
+	}
+	if claims.Exp == 0 {
+		return info, fmt.Errorf("%w: missing exp claim", ErrUnrecognizedToken)
+	}
+	info = TokenInfo{
+		Expiry:    time.Unix(int64(claims.Exp), 0),
+		UserId:    claims.UserId,
+		AccountId: claims.AccountId,
+		Roles:     claims.Roles,
+		Issuer:    claims.Iss,
+	}
+	return info, err
+}
@@ -0,0 +1,198 @@
+package sense
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRenewTokenSingleFlight(t *testing.T) {
+	f := newFakeSense(t)
+	f.tokenTTL = -time.Minute
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var mu sync.Mutex
+	var refreshed []string
+	s.OnTokenRefreshed(func(accessToken, refreshToken string) {
+		mu.Lock()
+		refreshed = append(refreshed, refreshToken)
+		mu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.RateZone(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := f.renewCount(); n != 1 {
+		t.Errorf("renewals = %d, want 1", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(refreshed) != 1 || refreshed[0] != "refresh" {
+		t.Errorf("OnTokenRefreshed calls = %v, want one call", refreshed)
+	}
+}
+
+func TestRenewTokenCallerCancel(t *testing.T) {
+	f := newFakeSense(t)
+	f.renewDelay = 100 * time.Millisecond
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first := make(chan error, 1)
+	go func() {
+		first <- s.RenewTokenContext(ctx)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if err = s.RenewTokenContext(context.Background()); err != nil {
+		t.Errorf("waiting caller err = %v, want the shared renewal to succeed", err)
+	}
+	if err = <-first; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled caller err = %v, want deadline exceeded", err)
+	}
+	if n := f.renewCount(); n != 1 {
+		t.Errorf("renewals = %d, want 1", n)
+	}
+}
+
+func TestStartTokenRefresher(t *testing.T) {
+	f := newFakeSense(t)
+	f.tokenTTL = 3 * time.Second
+	s, err := NewSenseApi("user", "pass", f.options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.StartTokenRefresher(ctx, 2*time.Second)
+	deadline := time.Now().Add(3 * time.Second)
+	for f.renewCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if f.renewCount() == 0 {
+		t.Fatal("token was not renewed in the background")
+	}
+	info, err := s.TokenInfo()
+	if err != nil || time.Until(info.Expiry) < 30*time.Minute {
+		t.Errorf("token expiry = %s, %v, want renewed token", info.Expiry, err)
+	}
+}
+
+func TestTokenRefresherUnrecognizedToken(t *testing.T) {
+	f := newFakeSense(t)
+	sess := Session{AccessToken: "opaque", RefreshToken: "refresh", UserId: 2, Monitors: []Monitor{{Id: 1}}}
+	s, err := NewSenseApiFromSession(sess, append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.refreshTokens(ctx, time.Minute, 10*time.Millisecond)
+	if n := f.renewCount(); n != 0 {
+		t.Errorf("renewals = %d, want none before sense rejects the token", n)
+	}
+}
+
+func TestTokenRefresherLogout(t *testing.T) {
+	f := newFakeSense(t)
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithLazyConnect())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Logout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// the margin exceeds the token lifetime, a running refresher would renew right away
+		s.refreshTokens(context.Background(), 2*time.Hour, 10*time.Millisecond)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refresher still running after logout")
+	}
+	if n := f.renewCount(); n != 0 {
+		t.Errorf("renewals = %d, want none after logout", n)
+	}
+}
+
+func TestTokenInfo(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	info, err := parseToken(testToken(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TokenInfo{Expiry: exp, UserId: 2, AccountId: 3, Roles: "user", Issuer: "sense"}
+	if !info.Expiry.Equal(want.Expiry) || info.UserId != want.UserId || info.AccountId != want.AccountId ||
+		info.Roles != want.Roles || info.Issuer != want.Issuer {
+		t.Errorf("info = %+v, want %+v", info, want)
+	}
+	if info.Expired(time.Minute) || !info.Expired(2*time.Hour) {
+		t.Error("unexpected Expired result")
+	}
+	for _, token := range []string{"", "abc", "a.b.c.d.e", "t1.2.eyJhbGciOiJIUzI1NiJ9.e30.sig"} {
+		if _, err := parseToken(token); !errors.Is(err, ErrUnrecognizedToken) {
+			t.Errorf("parseToken(%q) err = %v, want ErrUnrecognizedToken", token, err)
+		}
+	}
+}
+
+func TestUnrecognizedTokenRenewedOnUnauthorized(t *testing.T) {
+	f := newFakeSense(t)
+	f.mux.HandleFunc("/app/monitors/1/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") == "bearer opaque" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"monitor_id":1}`))
+	})
+	sess := Session{AccessToken: "opaque", RefreshToken: "refresh", UserId: 2, Monitors: []Monitor{{Id: 1}}}
+	opts := append(f.options(), WithLazyConnect(), WithRetryPolicy(NoRetry))
+	s, err := NewSenseApiFromSession(sess, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.TokenInfo(); !errors.Is(err, ErrUnrecognizedToken) {
+		t.Errorf("TokenInfo err = %v, want ErrUnrecognizedToken", err)
+	}
+	if _, err = s.MonitorStatus(context.Background()); err != nil {
+		t.Fatalf("expected the rejected token to be renewed: %v", err)
+	}
+	if n := f.renewCount(); n != 1 {
+		t.Errorf("renewals = %d, want 1", n)
+	}
+
+	sess.RefreshToken = "revoked"
+	s, err = NewSenseApiFromSession(sess, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failure *AuthEvent
+	s.OnAuthFailure(func(ev AuthEvent) {
+		failure = &ev
+	})
+	if _, err = s.MonitorStatus(context.Background()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+	if failure == nil || !errors.Is(failure.TokenErr, ErrUnrecognizedToken) {
+		t.Errorf("failure event = %+v, want TokenErr ErrUnrecognizedToken", failure)
+	}
+}
@@ -0,0 +1,403 @@
+package sense
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	encryptedStoreVersion = 1
+	pbkdf2Iterations      = 100000
+	saltSize              = 16
+	keySize               = 32
+)
+
+// Session credentials needed to resume a login without the password
+type Session struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	UserId       int       `json:"user_id"`
+	AccountId    int       `json:"account_id"`
+	Monitors     []Monitor `json:"monitors"`
+	// DeviceId client device identity the tokens were issued to
+	DeviceId string `json:"device_id,omitempty"`
+	// Email login the tokens were issued to
+	Email string `json:"email,omitempty"`
+}
+
+// TokenStore persists the session between process starts
+// implementations must be safe for concurrent use
+type TokenStore interface {
+	// Load return ErrNoSession if nothing has been saved yet
+	Load() (*Session, error)
+	Save(sess *Session) error
+	Clear() error
+}
+
+// WithTokenStore resume the session saved in store instead of logging in and save the session
+// after every login and token renewal. NewSenseApi only resumes a session saved for the same
+// username and logs in with the password if the saved session can no longer be renewed
+func WithTokenStore(store TokenStore) Option {
+	return func(s *SenseApi) error {
+		if store == nil {
+			return errors.New("token store must not be nil")
+		}
+		s.tokenStore = store
+		return nil
+	}
+}
+
+// NewSenseApiFromSession create a client from a saved session without logging in
+// and connect to the realtime feed like NewSenseApi
+func NewSenseApiFromSession(sess Session, opts ...Option) (s *SenseApi, err error) {
+	s, err = New(opts...)
+	if err != nil {
+		return s, err
+	}
+	if sess.AccessToken == "" && sess.RefreshToken == "" {
+		return s, errors.New("session has no access or refresh token")
+	}
+	s.resume(&sess)
+	s.connectEagerly(context.Background())
+	return s, err
+}
+
+// Session current session, save it to resume later with NewSenseApiFromSession
+func (s *SenseApi) Session() Session {
+	s.authMutex.RLock()
+	defer s.authMutex.RUnlock()
+	return s.session()
+}
+
+// session callers must hold authMutex
+func (s *SenseApi) session() Session {
+	return Session{
+		AccessToken:  s.authRes.AccessToken,
+		RefreshToken: s.authRes.RefreshToken,
+		UserId:       s.authRes.UserId,
+		AccountId:    s.authRes.AccountId,
+		Monitors:     append([]Monitor(nil), s.authRes.Monitors...),
+		DeviceId:     s.deviceId,
+		Email:        s.email,
+	}
+}
+
+// resume seed the auth state and device id from sess
+func (s *SenseApi) resume(sess *Session) {
+	s.authMutex.Lock()
+	if sess.DeviceId != "" && !s.fixedDeviceId {
+		s.deviceId = sess.DeviceId
+	}
+	s.authMutex.Unlock()
+	s.authSet(AuthRes{
+		Authorized:   true,
+		AccessToken:  sess.AccessToken,
+		RefreshToken: sess.RefreshToken,
+		UserId:       sess.UserId,
+		AccountId:    sess.AccountId,
+		Monitors:     sess.Monitors,
+	}, sess.Email)
+}
+
+// loadSession read the session from the token store, nil if there is none
+func (s *SenseApi) loadSession() (sess *Session, err error) {
+	if s.tokenStore == nil {
+		return nil, err
+	}
+	sess, err = s.tokenStore.Load()
+	if errors.Is(err, ErrNoSession) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load session: %w", err)
+	}
+	return sess, err
+}
+
+// saveSession write the current session to the token store if one is configured
+func (s *SenseApi) saveSession() (err error) {
+	if s.tokenStore == nil {
+		return err
+	}
+	sess := s.Session()
+	err = s.tokenStore.Save(&sess)
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return err
+}
+
+// loginOrResume resume the stored session if it belongs to username, otherwise log in.
+// an expired resumed session is renewed right away and replaced by a login if that fails,
+// with an empty username any stored session is resumed
+func (s *SenseApi) loginOrResume(ctx context.Context, username, password string) (err error) {
+	sess, err := s.loadSession()
+	if err != nil {
+		return err
+	}
+	if sess == nil || (username != "" && !strings.EqualFold(sess.Email, username)) {
+		return s.authenticate(ctx, username, password, false)
+	}
+	s.resume(sess)
+	token := s.accessToken()
+	if token != "" && !s.tokenExpired(token) {
+		return err
+	}
+	err = s.renewToken(ctx, token)
+	if err != nil && username != "" {
+		return s.authenticate(ctx, username, password, false)
+	}
+	return err
+}
+
+// MemoryTokenStore keeps the session in memory, e.g. to share it between clients in one process
+type MemoryTokenStore struct {
+	mutex sync.Mutex
+	sess  *Session
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (m *MemoryTokenStore) Load() (*Session, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.sess == nil {
+		return nil, ErrNoSession
+	}
+	sess := *m.sess
+	return &sess, nil
+}
+
+func (m *MemoryTokenStore) Save(sess *Session) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	cp := *sess
+	m.sess = &cp
+	return nil
+}
+
+func (m *MemoryTokenStore) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sess = nil
+	return nil
+}
+
+// FileTokenStore keeps the session as plain json in a file readable only by the owner
+type FileTokenStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (f *FileTokenStore) Load() (*Session, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	b, err := readStoreFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	sess := &Session{}
+	err = json.Unmarshal(b, sess)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (f *FileTokenStore) Save(sess *Session) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return writeStoreFile(f.path, b)
+}
+
+func (f *FileTokenStore) Clear() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return removeStoreFile(f.path)
+}
+
+// EncryptedFileTokenStore keeps the session in a file encrypted with AES-256-GCM,
+// the key is derived from a passphrase with PBKDF2-SHA256
+type EncryptedFileTokenStore struct {
+	mutex      sync.Mutex
+	path       string
+	passphrase []byte
+}
+
+type encryptedEnvelope struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func NewEncryptedFileTokenStore(path, passphrase string) (*EncryptedFileTokenStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	return &EncryptedFileTokenStore{path: path, passphrase: []byte(passphrase)}, nil
+}
+
+func (e *EncryptedFileTokenStore) Load() (*Session, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	b, err := readStoreFile(e.path)
+	if err != nil {
+		return nil, err
+	}
+	env := encryptedEnvelope{}
+	err = json.Unmarshal(b, &env)
+	if err != nil {
+		return nil, err
+	}
+	if env.Version != encryptedStoreVersion {
+		return nil, fmt.Errorf("unsupported token store version %d", env.Version)
+	}
+	gcm, err := newGCM(e.passphrase, env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, errors.New("decrypt session: wrong passphrase or corrupted file")
+	}
+	sess := &Session{}
+	err = json.Unmarshal(plain, sess)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (e *EncryptedFileTokenStore) Save(sess *Session) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	plain, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	env := encryptedEnvelope{Version: encryptedStoreVersion, Salt: make([]byte, saltSize)}
+	_, err = rand.Read(env.Salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(e.passphrase, env.Salt)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	_, err = rand.Read(env.Nonce)
+	if err != nil {
+		return err
+	}
+	env.Data = gcm.Seal(nil, env.Nonce, plain, nil)
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return writeStoreFile(e.path, b)
+}
+
+func (e *EncryptedFileTokenStore) Clear() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return removeStoreFile(e.path)
+}
+
+func newGCM(passphrase, salt []byte) (cipher.AEAD, error) {
+	if len(salt) != saltSize {
+		return nil, errors.New("invalid salt")
+	}
+	block, err := aes.NewCipher(pbkdf2Key(passphrase, salt, pbkdf2Iterations, keySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2Key RFC 8018 PBKDF2 with HMAC-SHA256
+func pbkdf2Key(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	counter := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		prf.Write(counter)
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+func readStoreFile(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSession
+	}
+	return b, err
+}
+
+// writeStoreFile replace path atomically with a file only readable by the owner
+func writeStoreFile(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(b)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func removeStoreFile(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package sense
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPbkdf2Key(t *testing.T) {
+	// RFC 7914 section 11 PBKDF2-HMAC-SHA256 test vector
+	got := hex.EncodeToString(pbkdf2Key([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("pbkdf2Key = %s, want %s", got, want)
+	}
+}
+
+func TestTokenStores(t *testing.T) {
+	dir := t.TempDir()
+	encrypted, err := NewEncryptedFileTokenStore(filepath.Join(dir, "enc.json"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]TokenStore{
+		"memory":    NewMemoryTokenStore(),
+		"file":      NewFileTokenStore(filepath.Join(dir, "plain.json")),
+		"encrypted": encrypted,
+	}
+	sess := &Session{AccessToken: "access", RefreshToken: "refresh", UserId: 2, Monitors: []Monitor{{Id: 1}}}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(); !errors.Is(err, ErrNoSession) {
+				t.Fatalf("empty load err = %v, want ErrNoSession", err)
+			}
+			if err := store.Save(sess); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.AccessToken != "access" || got.RefreshToken != "refresh" || got.UserId != 2 ||
+				len(got.Monitors) != 1 || got.Monitors[0].Id != 1 {
+				t.Errorf("loaded %+v, want %+v", got, sess)
+			}
+			if err := store.Clear(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Load(); !errors.Is(err, ErrNoSession) {
+				t.Errorf("load after clear err = %v, want ErrNoSession", err)
+			}
+		})
+	}
+
+	_ = encrypted.Save(sess)
+	if info, err := os.Stat(encrypted.path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("encrypted store file mode = %v, %v, want 0600", info.Mode(), err)
+	}
+	wrong, _ := NewEncryptedFileTokenStore(encrypted.path, "wrong")
+	if _, err := wrong.Load(); err == nil {
+		t.Error("expected error loading with wrong passphrase")
+	}
+}
+
+func TestWithTokenStore(t *testing.T) {
+	f := newFakeSense(t)
+	store := NewMemoryTokenStore()
+	s, err := NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+	saved, err := store.Load()
+	if err != nil || saved.RefreshToken != "refresh" || saved.UserId != 2 {
+		t.Fatalf("saved session = %+v, %v", saved, err)
+	}
+
+	// a second login would now fail, the client must resume the saved session instead
+	f.mfaCode = "123456"
+	s, err = NewSenseApi("user", "pass", append(f.options(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	expired := *saved
+	expired.AccessToken = testToken(time.Now().Add(-time.Hour))
+	s, err = NewSenseApiFromSession(expired, append(f.options(), WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RateZone(); err != nil {
+		t.Fatal(err)
+	}
+	renewed, _ := store.Load()
+	if renewed.AccessToken == expired.AccessToken || renewed.AccessToken != s.Session().AccessToken {
+		t.Error("renewed token was not saved to the store")
+	}
+}
+
+func TestWithTokenStoreFallback(t *testing.T) {
+	f := newFakeSense(t)
+	opts := append(f.options(), WithLazyConnect(), WithRetryPolicy(NoRetry))
+	store := NewMemoryTokenStore()
+	_ = store.Save(&Session{
+		AccessToken:  testToken(time.Now().Add(-time.Hour)),
+		RefreshToken: "revoked",
+		UserId:       2,
+		Email:        "user",
+	})
+	s, err := NewSenseApi("user", "pass", append(opts, WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RateZone(); err != nil {
+		t.Fatalf("expected a login after the revoked session: %v", err)
+	}
+	if saved, _ := store.Load(); saved.RefreshToken != "refresh" || saved.Email != "user" {
+		t.Errorf("saved session = %+v, want the new login", saved)
+	}
+
+	// a valid session of another user must not be resumed
+	_ = store.Save(&Session{AccessToken: testToken(time.Now().Add(time.Hour)), RefreshToken: "other", UserId: 9, Email: "other"})
+	s, err = NewSenseApi("USER", "pass", append(opts, WithTokenStore(store))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess := s.Session(); sess.UserId != 2 || sess.RefreshToken != "refresh" {
+		t.Errorf("session = %+v, want a login as user", sess)
+	}
+}
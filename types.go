@@ -2,6 +2,8 @@ package sense
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
@@ -10,161 +12,145 @@ import (
 )
 
 type AuthRes struct {
-	MfaToken    string `json:"mfa_token"`
-	MfaType     string `json:"mfa_type"`
-	Status      string `json:"status"`
-	ErrorReason string `json:"error_reason"`
-	Authorized  bool   `json:"authorized"`
-	AccountId   int    `json:"account_id"`
-	UserId      int    `json:"user_id"`
-	AccessToken string `json:"access_token"`
-	Settings    struct {
-		UserId   int `json:"user_id"`
-		Settings struct {
-			Notifications struct {
-				Field1 struct {
-					NewNamedDevicePush   bool `json:"new_named_device_push"`
-					NewNamedDeviceEmail  bool `json:"new_named_device_email"`
-					MonitorOfflinePush   bool `json:"monitor_offline_push"`
-					MonitorOfflineEmail  bool `json:"monitor_offline_email"`
-					MonitorMonthlyEmail  bool `json:"monitor_monthly_email"`
-					AlwaysOnChangePush   bool `json:"always_on_change_push"`
-					ComparisonChangePush bool `json:"comparison_change_push"`
-					NewPeakPush          bool `json:"new_peak_push"`
-					NewPeakEmail         bool `json:"new_peak_email"`
-					MonthlyChangePush    bool `json:"monthly_change_push"`
-					WeeklyChangePush     bool `json:"weekly_change_push"`
-					DailyChangePush      bool `json:"daily_change_push"`
-					GeneratorOnPush      bool `json:"generator_on_push"`
-					GeneratorOffPush     bool `json:"generator_off_push"`
-					TimeOfUse            bool `json:"time_of_use"`
-				} `json:"187899"`
-				Field2 struct {
-					NewNamedDevicePush   bool `json:"new_named_device_push"`
-					NewNamedDeviceEmail  bool `json:"new_named_device_email"`
-					MonitorOfflinePush   bool `json:"monitor_offline_push"`
-					MonitorOfflineEmail  bool `json:"monitor_offline_email"`
-					MonitorMonthlyEmail  bool `json:"monitor_monthly_email"`
-					AlwaysOnChangePush   bool `json:"always_on_change_push"`
-					ComparisonChangePush bool `json:"comparison_change_push"`
-					NewPeakPush          bool `json:"new_peak_push"`
-					NewPeakEmail         bool `json:"new_peak_email"`
-					MonthlyChangePush    bool `json:"monthly_change_push"`
-					WeeklyChangePush     bool `json:"weekly_change_push"`
-					DailyChangePush      bool `json:"daily_change_push"`
-					GeneratorOnPush      bool `json:"generator_on_push"`
-					GeneratorOffPush     bool `json:"generator_off_push"`
-					TimeOfUse            bool `json:"time_of_use"`
-				} `json:"188074"`
-			} `json:"notifications"`
-			LabsEnabled bool `json:"labs_enabled"`
-		} `json:"settings"`
-		Version int `json:"version"`
+	MfaToken     string       `json:"mfa_token"`
+	MfaType      string       `json:"mfa_type"`
+	Status       string       `json:"status"`
+	ErrorReason  string       `json:"error_reason"`
+	Authorized   bool         `json:"authorized"`
+	AccountId    int          `json:"account_id"`
+	UserId       int          `json:"user_id"`
+	AccessToken  string       `json:"access_token"`
+	Settings     UserSettings `json:"settings"`
+	Monitors     []Monitor    `json:"monitors"`
+	BridgeServer string       `json:"bridge_server"`
+	DateCreated  time.Time    `json:"date_created"`
+	TotpEnabled  bool         `json:"totp_enabled"`
+	AbCohort     string       `json:"ab_cohort"`
+	RefreshToken string       `json:"refresh_token"`
+}
+
+// UserSettings account settings, notification preferences are keyed by monitor id
+type UserSettings struct {
+	UserId   int `json:"user_id"`
+	Settings struct {
+		Notifications map[int]NotificationSettings `json:"notifications"`
+		LabsEnabled   bool                         `json:"labs_enabled"`
 	} `json:"settings"`
-	Monitors []struct {
-		Id              int    `json:"id"`
-		SerialNumber    string `json:"serial_number"`
-		TimeZone        string `json:"time_zone"`
-		SolarConnected  bool   `json:"solar_connected"`
-		SolarConfigured bool   `json:"solar_configured"`
-		Online          bool   `json:"online"`
-		Attributes      struct {
-			Id                  int         `json:"id"`
-			Name                string      `json:"name"`
-			State               string      `json:"state"`
-			Cost                float64     `json:"cost"`
-			SellBackRate        float64     `json:"sell_back_rate"`
-			UserSetCost         bool        `json:"user_set_cost"`
-			CycleStart          int         `json:"cycle_start"`
-			BasementType        string      `json:"basement_type"`
-			HomeSizeType        string      `json:"home_size_type"`
-			HomeType            string      `json:"home_type"`
-			NumberOfOccupants   string      `json:"number_of_occupants"`
-			OccupancyType       string      `json:"occupancy_type"`
-			YearBuiltType       string      `json:"year_built_type"`
-			PostalCode          string      `json:"postal_code"`
-			ElectricityCost     interface{} `json:"electricity_cost"`
-			ShowCost            bool        `json:"show_cost"`
-			TouEnabled          bool        `json:"tou_enabled"`
-			SolarTouEnabled     bool        `json:"solar_tou_enabled"`
-			PowerRegion         interface{} `json:"power_region"`
-			UserSetSellBackRate bool        `json:"user_set_sell_back_rate"`
-		} `json:"attributes"`
-		SignalCheckCompletedTime time.Time     `json:"signal_check_completed_time"`
-		DataSharing              []interface{} `json:"data_sharing"`
-		EthernetSupported        bool          `json:"ethernet_supported"`
-		AuxIgnore                bool          `json:"aux_ignore"`
-		AuxPort                  string        `json:"aux_port"`
-		HardwareType             string        `json:"hardware_type"`
-	} `json:"monitors"`
-	BridgeServer string    `json:"bridge_server"`
-	DateCreated  time.Time `json:"date_created"`
-	TotpEnabled  bool      `json:"totp_enabled"`
-	AbCohort     string    `json:"ab_cohort"`
-	RefreshToken string    `json:"refresh_token"`
+	Version int `json:"version"`
 }
 
+// NotificationSettings push and email notification preferences of one monitor
+type NotificationSettings struct {
+	NewNamedDevicePush   bool `json:"new_named_device_push"`
+	NewNamedDeviceEmail  bool `json:"new_named_device_email"`
+	MonitorOfflinePush   bool `json:"monitor_offline_push"`
+	MonitorOfflineEmail  bool `json:"monitor_offline_email"`
+	MonitorMonthlyEmail  bool `json:"monitor_monthly_email"`
+	AlwaysOnChangePush   bool `json:"always_on_change_push"`
+	ComparisonChangePush bool `json:"comparison_change_push"`
+	NewPeakPush          bool `json:"new_peak_push"`
+	NewPeakEmail         bool `json:"new_peak_email"`
+	MonthlyChangePush    bool `json:"monthly_change_push"`
+	WeeklyChangePush     bool `json:"weekly_change_push"`
+	DailyChangePush      bool `json:"daily_change_push"`
+	GeneratorOnPush      bool `json:"generator_on_push"`
+	GeneratorOffPush     bool `json:"generator_off_push"`
+	TimeOfUse            bool `json:"time_of_use"`
+}
+
+type Monitor struct {
+	Id              int    `json:"id"`
+	SerialNumber    string `json:"serial_number"`
+	TimeZone        string `json:"time_zone"`
+	SolarConnected  bool   `json:"solar_connected"`
+	SolarConfigured bool   `json:"solar_configured"`
+	Online          bool   `json:"online"`
+	Attributes      struct {
+		Id                  int              `json:"id"`
+		Name                string           `json:"name"`
+		State               string           `json:"state"`
+		Cost                float64          `json:"cost"`
+		SellBackRate        float64          `json:"sell_back_rate"`
+		UserSetCost         bool             `json:"user_set_cost"`
+		CycleStart          int              `json:"cycle_start"`
+		BasementType        BasementType     `json:"basement_type"`
+		HomeSizeType        HomeSizeType     `json:"home_size_type"`
+		HomeType            HomeType         `json:"home_type"`
+		NumberOfOccupants   OccupantsType    `json:"number_of_occupants"`
+		OccupancyType       OccupancyType    `json:"occupancy_type"`
+		YearBuiltType       YearBuiltType    `json:"year_built_type"`
+		PostalCode          string           `json:"postal_code"`
+		ElectricityCost     *ElectricityCost `json:"electricity_cost"` // nil when sense does not embed it
+		ShowCost            bool             `json:"show_cost"`
+		TouEnabled          bool             `json:"tou_enabled"`
+		SolarTouEnabled     bool             `json:"solar_tou_enabled"`
+		PowerRegion         interface{}      `json:"power_region"`
+		UserSetSellBackRate bool             `json:"user_set_sell_back_rate"`
+	} `json:"attributes"`
+	SignalCheckCompletedTime time.Time     `json:"signal_check_completed_time"`
+	DataSharing              []interface{} `json:"data_sharing"`
+	EthernetSupported        bool          `json:"ethernet_supported"`
+	AuxIgnore                bool          `json:"aux_ignore"`
+	AuxPort                  string        `json:"aux_port"`
+	HardwareType             string        `json:"hardware_type"`
+}
+
+// SenseApi sense api client
+// all exported methods are safe for concurrent use by multiple goroutines
 type SenseApi struct {
-	ws           *websocket.Conn
-	wssEndpoint  string
 	refreshToken string
 	authRes      AuthRes
 	mutex        sync.RWMutex
 	messages     []RealTime
 	readingAsync bool
+
+	// authMutex guards authRes, email, loggedOut, defaultMonitorId and deviceId
+	authMutex        sync.RWMutex
+	email            string
+	loggedOut        bool
+	defaultMonitorId int
+	// deviceId client device identity sent to sense, fixedDeviceId is set when it came from WithDeviceID
+	deviceId      string
+	fixedDeviceId bool
+	// feed realtime feed of the default monitor, feeds other open monitor feeds guarded by feedsMutex
+	feed       *MonitorFeed
+	feeds      map[*MonitorFeed]struct{}
+	feedsMutex sync.Mutex
+
+	// renewMutex guards renewing, the in flight token renewal, and lastRelogin
+	renewMutex       sync.Mutex
+	renewing         *renewCall
+	lastRelogin      time.Time
+	onTokenRefreshed []func(accessToken, refreshToken string)
+	// hooks auth lifecycle hooks, guarded by authMutex
+	hooks authHooks
+
+	httpClient  *http.Client
+	baseUrl     string
+	realtimeUrl *url.URL
+	wsDialer    *websocket.Dialer
+	userAgent   string
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+	clockSkew   time.Duration
+	cache       *responseCache
+	mfaProvider MFAProvider
+	credentials CredentialProvider
+	// pendingMfa challenge returned by Login awaiting CompleteMFA, guarded by authMutex
+	pendingMfa  *MFAChallenge
+	tokenStore  TokenStore
+	lazyConnect bool
 }
 
 type AlwaysOn struct {
-	Alerts struct {
-		Allowed bool `json:"allowed"`
-		Enabled bool `json:"enabled"`
-	} `json:"alerts"`
-	Device struct {
-		Id        string `json:"id"`
-		MonitorId int    `json:"monitorId"`
-		Name      string `json:"name"`
-		Icon      string `json:"icon"`
-		Tags      struct {
-			DefaultUserDeviceType       string `json:"DefaultUserDeviceType"`
-			DeviceListAllowed           string `json:"DeviceListAllowed"`
-			SSIEnabled                  string `json:"SSIEnabled"`
-			TimelineAllowed             string `json:"TimelineAllowed"`
-			UserEditable                string `json:"UserEditable"`
-			UserDeviceTypeDisplayString string `json:"UserDeviceTypeDisplayString"`
-			UserDeviceType              string `json:"UserDeviceType"`
-		} `json:"tags"`
-	} `json:"device"`
-	LastState     interface{} `json:"last_state"`
-	Notes         interface{} `json:"notes"`
-	Info          string      `json:"info"`
-	LastStateTime interface{} `json:"last_state_time"`
-	Usage         struct {
-		AvgMonthlyKWH    float64 `json:"avg_monthly_KWH"`
-		AvgMonthlyPct    float64 `json:"avg_monthly_pct"`
-		AvgWatts         float64 `json:"avg_watts"`
-		YearlyKWH        float64 `json:"yearly_KWH"`
-		YearlyText       string  `json:"yearly_text"`
-		YearlyCost       int     `json:"yearly_cost"`
-		AvgMonthlyCost   int     `json:"avg_monthly_cost"`
-		CurrentAoWattage int     `json:"current_ao_wattage"`
-		Comparison       struct {
-			ComparisonText string   `json:"comparison_text"`
-			TercileStrings []string `json:"tercile_strings"`
-			CohortMarker   int      `json:"cohort_marker"`
-			Cohort         struct {
-				Id         int    `json:"id"`
-				PostalCode string `json:"postal_code"`
-				AreaCode   string `json:"area_code"`
-				State      string `json:"state"`
-				HomeSize   string `json:"home_size"`
-				Location   string `json:"location"`
-			} `json:"cohort"`
-			Title      string  `json:"title"`
-			W          float64 `json:"w"`
-			CohortAvgW float64 `json:"cohort_avg_w"`
-		} `json:"comparison"`
-	} `json:"usage"`
-	AlwaysOn struct {
+	Alerts        DeviceAlerts `json:"alerts"`
+	Device        DeviceInfo   `json:"device"`
+	LastState     interface{}  `json:"last_state"`
+	Notes         interface{}  `json:"notes"`
+	Info          string       `json:"info"`
+	LastStateTime interface{}  `json:"last_state_time"`
+	Usage         DeviceUsage  `json:"usage"`
+	AlwaysOn      struct {
 		Description  string  `json:"description"`
 		TotalWatts   float64 `json:"total_watts"`
 		UnknownWatts float64 `json:"unknown_watts"`
@@ -173,50 +159,122 @@ type AlwaysOn struct {
 			W  float64 `json:"w"`
 		} `json:"devices"`
 	} `json:"always_on"`
-	Timeline struct {
-		Visible bool `json:"visible"`
-		Allowed bool `json:"allowed"`
-	} `json:"timeline"`
-	Blurb struct {
-		Type    string `json:"type"`
-		Content string `json:"content"`
-	} `json:"blurb"`
+	Timeline DeviceTimeline `json:"timeline"`
+	Blurb    DeviceBlurb    `json:"blurb"`
+}
+
+// DeviceDetail detail page data of a single device
+type DeviceDetail struct {
+	Alerts    DeviceAlerts `json:"alerts"`
+	Device    DeviceInfo   `json:"device"`
+	LastState string       `json:"last_state"`
+	// LastStateTime zero if the device has not changed state yet
+	LastStateTime time.Time      `json:"last_state_time"`
+	Notes         string         `json:"notes"`
+	Info          string         `json:"info"`
+	Usage         DeviceUsage    `json:"usage"`
+	Timeline      DeviceTimeline `json:"timeline"`
+	Blurb         DeviceBlurb    `json:"blurb"`
+}
+
+type DeviceInfo struct {
+	Id        string     `json:"id"`
+	MonitorId int        `json:"monitorId"`
+	Name      string     `json:"name"`
+	Icon      string     `json:"icon"`
+	Make      string     `json:"make,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	Location  string     `json:"location,omitempty"`
+	Tags      DeviceTags `json:"tags"`
+}
+
+type DeviceTags struct {
+	DefaultUserDeviceType       string `json:"DefaultUserDeviceType"`
+	DeviceListAllowed           string `json:"DeviceListAllowed"`
+	SSIEnabled                  string `json:"SSIEnabled"`
+	TimelineAllowed             string `json:"TimelineAllowed"`
+	UserEditable                string `json:"UserEditable"`
+	UserDeviceTypeDisplayString string `json:"UserDeviceTypeDisplayString"`
+	UserDeviceType              string `json:"UserDeviceType"`
+	// DateFirstUsage day the device was first seen, e.g. 2021-05-30
+	DateFirstUsage string `json:"DateFirstUsage,omitempty"`
+}
+
+type DeviceAlerts struct {
+	Allowed bool `json:"allowed"`
+	Enabled bool `json:"enabled"`
+}
+
+// DeviceUsage energy use of a device, the run counts and duration are only set for devices that turn on and off
+type DeviceUsage struct {
+	AvgMonthlyKWH    float64 `json:"avg_monthly_KWH"`
+	AvgMonthlyPct    float64 `json:"avg_monthly_pct"`
+	AvgWatts         float64 `json:"avg_watts"`
+	YearlyKWH        float64 `json:"yearly_KWH"`
+	YearlyText       string  `json:"yearly_text"`
+	YearlyCost       int     `json:"yearly_cost"`
+	AvgMonthlyCost   int     `json:"avg_monthly_cost"`
+	CurrentAoWattage int     `json:"current_ao_wattage"`
+	// AvgDuration average runtime in seconds
+	AvgDuration      float64    `json:"avg_duration"`
+	AvgMonthlyRuns   float64    `json:"avg_monthly_runs"`
+	CurrentMonthRuns int        `json:"current_month_runs"`
+	CurrentMonthKWH  float64    `json:"current_month_KWH"`
+	Comparison       Comparison `json:"comparison"`
+}
+
+// Comparison usage compared to a cohort of similar homes
+type Comparison struct {
+	ComparisonText string   `json:"comparison_text"`
+	TercileStrings []string `json:"tercile_strings"`
+	CohortMarker   int      `json:"cohort_marker"`
+	Cohort         Cohort   `json:"cohort"`
+	Title          string   `json:"title"`
+	W              float64  `json:"w"`
+	CohortAvgW     float64  `json:"cohort_avg_w"`
+}
+
+type Cohort struct {
+	Id         int    `json:"id"`
+	PostalCode string `json:"postal_code"`
+	AreaCode   string `json:"area_code"`
+	State      string `json:"state"`
+	HomeSize   string `json:"home_size"`
+	Location   string `json:"location"`
+}
+
+type DeviceTimeline struct {
+	Visible bool `json:"visible"`
+	Allowed bool `json:"allowed"`
+}
+
+type DeviceBlurb struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
 }
 
 type TimeLineRes struct {
-	MoreItems   bool          `json:"more_items"`
-	StickyItems []interface{} `json:"sticky_items"`
-	UserId      int           `json:"user_id"`
-	Items       []struct {
-		Time           time.Time `json:"time"`
-		Type           string    `json:"type"`
-		Icon           string    `json:"icon"`
-		Body           string    `json:"body"`
-		Destination    string    `json:"destination"`
-		DeviceId       string    `json:"device_id"`
-		DeviceState    string    `json:"device_state"`
-		ShowAction     bool      `json:"show_action"`
-		AllowSticky    bool      `json:"allow_sticky"`
-		UserDeviceType string    `json:"user_device_type"`
-		StartTime      time.Time `json:"start_time,omitempty"`
-		Children       []struct {
-			Time        time.Time `json:"time"`
-			Type        string    `json:"type"`
-			Body        string    `json:"body"`
-			DeviceId    string    `json:"device_id"`
-			ShowAction  bool      `json:"show_action"`
-			AllowSticky bool      `json:"allow_sticky"`
-			StartTime   time.Time `json:"start_time,omitempty"`
-			Children    []struct {
-				Time        time.Time `json:"time"`
-				Type        string    `json:"type"`
-				DeviceId    string    `json:"device_id"`
-				ShowAction  bool      `json:"show_action"`
-				AllowSticky bool      `json:"allow_sticky"`
-			} `json:"children,omitempty"`
-		} `json:"children,omitempty"`
-		Count int `json:"count,omitempty"`
-	} `json:"items"`
+	MoreItems   bool           `json:"more_items"`
+	StickyItems []interface{}  `json:"sticky_items"`
+	UserId      int            `json:"user_id"`
+	Items       []TimelineItem `json:"items"`
+}
+
+// TimelineItem timeline event, grouped events carry the individual events in Children
+type TimelineItem struct {
+	Time           time.Time      `json:"time"`
+	Type           string         `json:"type"`
+	Icon           string         `json:"icon,omitempty"`
+	Body           string         `json:"body,omitempty"`
+	Destination    string         `json:"destination,omitempty"`
+	DeviceId       string         `json:"device_id"`
+	DeviceState    string         `json:"device_state,omitempty"`
+	ShowAction     bool           `json:"show_action"`
+	AllowSticky    bool           `json:"allow_sticky"`
+	UserDeviceType string         `json:"user_device_type,omitempty"`
+	StartTime      time.Time      `json:"start_time,omitempty"`
+	Children       []TimelineItem `json:"children,omitempty"`
+	Count          int            `json:"count,omitempty"`
 }
 
 type DevicesOverview struct {
@@ -236,7 +294,7 @@ type DevicesOverview struct {
 			DeviceListAllowed     string    `json:"DeviceListAllowed"`
 			ModelCreatedVersion   string    `json:"ModelCreatedVersion,omitempty"`
 			ModelUpdatedVersion   string    `json:"ModelUpdatedVersion,omitempty"`
-			NameUseredit          string    `json:"name_useredit,omitempty"`
+			NameUseredit          string    `json:"name_useredit,omitempty"` // set once the user renamed the device
 			OriginalName          string    `json:"OriginalName,omitempty"`
 			PeerNames             []struct {
 				Name                        string  `json:"Name"`
@@ -399,7 +457,9 @@ const (
 )
 
 type RealTime struct {
-	Payload struct {
+	// MonitorId monitor the frame was received from
+	MonitorId int `json:"-"`
+	Payload   struct {
 		Online  bool      `json:"online"`
 		Voltage []float64 `json:"voltage"`
 		Frame   int       `json:"frame"`
@@ -479,23 +539,8 @@ func (r RealTime) String() string {
 }
 
 type HistoryCompare struct {
-	PeriodTitle         string `json:"period_title"`
-	SummaryCohortMarker int    `json:"summary_cohort_marker"`
-	Comparisons         []struct {
-		ComparisonText string   `json:"comparison_text"`
-		TercileStrings []string `json:"tercile_strings"`
-		CohortMarker   int      `json:"cohort_marker"`
-		Cohort         struct {
-			Id         int    `json:"id"`
-			PostalCode string `json:"postal_code"`
-			AreaCode   string `json:"area_code"`
-			State      string `json:"state"`
-			HomeSize   string `json:"home_size"`
-			Location   string `json:"location"`
-		} `json:"cohort"`
-		Title      string  `json:"title"`
-		W          float64 `json:"w"`
-		CohortAvgW float64 `json:"cohort_avg_w"`
-	} `json:"comparisons"`
-	AvgW float64 `json:"avg_w"`
+	PeriodTitle         string       `json:"period_title"`
+	SummaryCohortMarker int          `json:"summary_cohort_marker"`
+	Comparisons         []Comparison `json:"comparisons"`
+	AvgW                float64      `json:"avg_w"`
 }